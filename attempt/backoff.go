@@ -0,0 +1,105 @@
+package attempt
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes the delay before each retry.
+//
+// Next is called with the retry number n, starting at 1 for the retry that
+// follows the first attempt, the previous delay (zero before the first retry)
+// and a random source owned by the Retrier. The Retrier clamps the result to
+// its Floor and Ceil.
+type Backoff interface {
+	Next(n int, prev time.Duration, rnd *rand.Rand) time.Duration
+}
+
+// Constant waits the same Delay before every retry.
+type Constant struct {
+	Delay time.Duration
+}
+
+func (b Constant) Next(int, time.Duration, *rand.Rand) time.Duration {
+	return b.Delay
+}
+
+// Linear waits Initial before the first retry and adds Step for each one after.
+type Linear struct {
+	Initial, Step time.Duration
+}
+
+func (b Linear) Next(n int, _ time.Duration, _ *rand.Rand) time.Duration {
+	return clampDuration(float64(b.Initial) + float64(b.Step)*float64(n-1))
+}
+
+// Exponential waits Initial before the first retry and multiplies by Rate for
+// each one after. A Rate below 1 is treated as 2.
+type Exponential struct {
+	Initial time.Duration
+	Rate    float64
+}
+
+func (b Exponential) Next(n int, _ time.Duration, _ *rand.Rand) time.Duration {
+	rate := b.Rate
+	if rate < 1 {
+		rate = 2
+	}
+	return clampDuration(float64(b.Initial) * math.Pow(rate, float64(n-1)))
+}
+
+// DecorrelatedJitter picks each delay uniformly between Base and three times
+// the previous delay, as described in the AWS architecture blog post
+// "Exponential Backoff And Jitter". Set the Retrier's Ceil to cap it.
+type DecorrelatedJitter struct {
+	Base time.Duration
+}
+
+func (b DecorrelatedJitter) Next(_ int, prev time.Duration, rnd *rand.Rand) time.Duration {
+	upper := float64(prev) * 3
+	if upper < float64(b.Base) {
+		return b.Base
+	}
+	return clampDuration(float64(b.Base) + rnd.Float64()*(upper-float64(b.Base)))
+}
+
+// FullJitter picks each delay uniformly between zero and min(Cap, Base*2^(n-1)).
+// A zero Cap means no cap. Leave the Retrier's Floor at zero so the low end of
+// the range is not clamped away.
+type FullJitter struct {
+	Base, Cap time.Duration
+}
+
+func (b FullJitter) Next(n int, _ time.Duration, rnd *rand.Rand) time.Duration {
+	upper := float64(b.Base) * math.Pow(2, float64(n-1))
+	if b.Cap > 0 && upper > float64(b.Cap) {
+		upper = float64(b.Cap)
+	}
+	return clampDuration(rnd.Float64() * upper)
+}
+
+// Fibonacci waits Initial multiplied by the n-th Fibonacci number, giving
+// 1, 1, 2, 3, 5, ... times Initial.
+type Fibonacci struct {
+	Initial time.Duration
+}
+
+func (b Fibonacci) Next(n int, _ time.Duration, _ *rand.Rand) time.Duration {
+	a, c := 0.0, 1.0
+	for i := 0; i < n && a < math.MaxInt64; i++ {
+		a, c = c, a+c
+	}
+	return clampDuration(float64(b.Initial) * a)
+}
+
+// clampDuration converts d to a Duration without overflowing.
+func clampDuration(d float64) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
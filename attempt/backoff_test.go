@@ -0,0 +1,127 @@
+package attempt
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func sequence(b Backoff, n int) []time.Duration {
+	rnd := rand.New(rand.NewSource(1))
+	out := make([]time.Duration, 0, n)
+	var prev time.Duration
+	for i := 1; i <= n; i++ {
+		prev = b.Next(i, prev, rnd)
+		out = append(out, prev)
+	}
+	return out
+}
+
+func assertSequence(t *testing.T, got, want []time.Duration) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestBackoff_Constant(t *testing.T) {
+	assertSequence(t, sequence(Constant{Delay: time.Second}, 3),
+		[]time.Duration{time.Second, time.Second, time.Second})
+}
+
+func TestBackoff_Linear(t *testing.T) {
+	assertSequence(t, sequence(Linear{Initial: time.Second, Step: 2 * time.Second}, 4),
+		[]time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 7 * time.Second})
+}
+
+func TestBackoff_Exponential(t *testing.T) {
+	assertSequence(t, sequence(Exponential{Initial: time.Second, Rate: 3}, 4),
+		[]time.Duration{time.Second, 3 * time.Second, 9 * time.Second, 27 * time.Second})
+
+	// Rates below 1 default to doubling.
+	assertSequence(t, sequence(Exponential{Initial: time.Second}, 3),
+		[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second})
+}
+
+func TestBackoff_ExponentialOverflow(t *testing.T) {
+	d := Exponential{Initial: time.Hour, Rate: 10}.Next(100, 0, nil)
+	if d != math.MaxInt64 {
+		t.Fatalf("expected saturation, got %v", d)
+	}
+}
+
+func TestBackoff_Fibonacci(t *testing.T) {
+	got := sequence(Fibonacci{Initial: time.Second}, 6)
+	want := []time.Duration{1, 1, 2, 3, 5, 8}
+	for i := range want {
+		want[i] *= time.Second
+	}
+	assertSequence(t, got, want)
+}
+
+func TestBackoff_DecorrelatedJitter(t *testing.T) {
+	b := DecorrelatedJitter{Base: 10 * time.Millisecond}
+	rnd := rand.New(rand.NewSource(1))
+
+	prev := time.Duration(0)
+	for i := 1; i <= 100; i++ {
+		d := b.Next(i, prev, rnd)
+		if d < b.Base {
+			t.Fatalf("delay %v below base", d)
+		}
+		if prev*3 > b.Base && d > prev*3 {
+			t.Fatalf("delay %v above 3x previous %v", d, prev)
+		}
+		prev = d
+	}
+}
+
+func TestBackoff_FullJitter(t *testing.T) {
+	b := FullJitter{Base: 10 * time.Millisecond, Cap: 50 * time.Millisecond}
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 1; i <= 100; i++ {
+		d := b.Next(i, 0, rnd)
+		upper := b.Cap
+		if i <= 3 {
+			upper = b.Base << (i - 1)
+		}
+		if d < 0 || d > upper {
+			t.Fatalf("retry %d: delay %v outside [0, %v]", i, d, upper)
+		}
+	}
+}
+
+func TestBackoff_Deterministic(t *testing.T) {
+	b := DecorrelatedJitter{Base: time.Millisecond}
+	assertSequence(t, sequence(b, 10), sequence(b, 10))
+}
+
+func TestRetrier_BackoffClamped(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	r := New(2*time.Millisecond, 3*time.Millisecond)
+	r.Backoff = Linear{Initial: time.Millisecond, Step: time.Millisecond}
+	r.Clock = clock
+
+	var delays []time.Duration
+	ctx := context.Background()
+	for i := 0; i < 5 && r.Wait(ctx); i++ {
+		delays = append(delays, r.Delay)
+	}
+	assertSequence(t, delays, []time.Duration{
+		0,
+		2 * time.Millisecond, // floor
+		2 * time.Millisecond,
+		3 * time.Millisecond,
+		3 * time.Millisecond, // ceil
+	})
+}
@@ -0,0 +1,256 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for calls rejected by an open CircuitBreaker.
+var ErrCircuitOpen = errors.New("attempt: circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// StateClosed lets every call through and counts failures.
+	StateClosed BreakerState = iota
+	// StateOpen rejects every call until Cooldown has elapsed.
+	StateOpen
+	// StateHalfOpen lets HalfOpenProbes calls through to test the dependency.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops calls to a failing dependency.
+//
+// A closed breaker trips open after ConsecutiveFailures failures in a row, or
+// once FailureRate of the calls within Window failed. An open breaker rejects
+// calls with ErrCircuitOpen for Cooldown, then moves to half-open and lets
+// HalfOpenProbes calls through: if all succeed it closes, and any failure opens
+// it again. Set a Retrier's Breaker to stop retrying while the breaker is open.
+// It is safe for concurrent use.
+type CircuitBreaker struct {
+	// Name identifies the breaker in OnStateChange.
+	Name string
+
+	// ConsecutiveFailures trips the breaker after that many failures in a row.
+	// Zero disables the check.
+	ConsecutiveFailures int
+
+	// FailureRate trips the breaker when the ratio of failed calls within
+	// Window reaches it, once at least MinCalls were made. Zero disables the
+	// check.
+	FailureRate float64
+	MinCalls    int
+	Window      time.Duration
+
+	// Cooldown is how long the breaker stays open before probing.
+	Cooldown time.Duration
+
+	// HalfOpenProbes is how many calls are let through while half-open.
+	// Values below 1 are treated as 1.
+	HalfOpenProbes int
+
+	// IsFailure classifies the errors passed to Record. Nil counts every
+	// non-nil error as a failure.
+	IsFailure func(err error) bool
+
+	// OnStateChange, when set, is called after every state transition.
+	// It runs outside the breaker's lock.
+	OnStateChange func(name string, from, to BreakerState)
+
+	// Clock is the source of time for Window and Cooldown.
+	// Nil means RealClock.
+	Clock Clock
+
+	mu          sync.Mutex
+	state       BreakerState
+	generation  uint64
+	consecutive int
+	openedAt    time.Time
+	probes      int
+	probed      int
+	window      window // counts successes and failures
+}
+
+// NewCircuitBreaker creates a breaker that opens after consecutiveFailures
+// failures in a row and probes again after cooldown.
+func NewCircuitBreaker(name string, consecutiveFailures int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Name:                name,
+		ConsecutiveFailures: consecutiveFailures,
+		Cooldown:            cooldown,
+		HalfOpenProbes:      1,
+	}
+}
+
+// State returns the current state. An open breaker whose cooldown has elapsed
+// reports StateHalfOpen.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	from := b.state
+	to := b.refresh()
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return to
+}
+
+// Allow reports whether a call may proceed, returning ErrCircuitOpen if not.
+// Every nil error must be followed by a call to Record with the returned
+// generation, which identifies the state the call was allowed in.
+func (b *CircuitBreaker) Allow() (generation uint64, err error) {
+	b.mu.Lock()
+	from := b.state
+	to := b.refresh()
+	generation = b.generation
+
+	switch to {
+	case StateOpen:
+		err = ErrCircuitOpen
+	case StateHalfOpen:
+		if b.probes >= b.maxProbes() {
+			err = ErrCircuitOpen
+		} else {
+			b.probes++
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return generation, err
+}
+
+// Record reports the outcome of a call that was allowed in generation.
+// Outcomes of calls allowed before the last state transition are ignored, so
+// a slow call started while closed can't count as a half-open probe.
+func (b *CircuitBreaker) Record(generation uint64, err error) {
+	failed := err != nil
+	if failed && b.IsFailure != nil {
+		failed = b.IsFailure(err)
+	}
+
+	b.mu.Lock()
+	from := b.state
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		if failed {
+			b.consecutive++
+			b.window.add(b.now(), b.Window, 0, 1)
+		} else {
+			b.consecutive = 0
+			b.window.add(b.now(), b.Window, 1, 0)
+		}
+		if b.tripped() {
+			b.open()
+		}
+	case StateHalfOpen:
+		b.probes--
+		if failed {
+			b.open()
+		} else if b.probed++; b.probed >= b.maxProbes() {
+			b.close()
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// Do runs fn if the breaker allows it and records the result.
+// It returns ErrCircuitOpen without calling fn while the breaker is open.
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	generation, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	b.Record(generation, err)
+	return err
+}
+
+// ready reports whether the breaker would currently let a call through,
+// without reserving a probe.
+func (b *CircuitBreaker) ready() bool {
+	return b.State() != StateOpen
+}
+
+// refresh moves an open breaker to half-open once Cooldown has elapsed and
+// returns the resulting state. b.mu must be held.
+func (b *CircuitBreaker) refresh() BreakerState {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.Cooldown {
+		b.state = StateHalfOpen
+		b.generation++
+		b.probes = 0
+		b.probed = 0
+	}
+	return b.state
+}
+
+// tripped reports whether a closed breaker should open. b.mu must be held.
+func (b *CircuitBreaker) tripped() bool {
+	if b.ConsecutiveFailures > 0 && b.consecutive >= b.ConsecutiveFailures {
+		return true
+	}
+	if b.FailureRate <= 0 {
+		return false
+	}
+
+	successes, failures := b.window.sum(b.now(), b.Window)
+	calls := successes + failures
+	return calls > 0 && calls >= b.MinCalls && float64(failures)/float64(calls) >= b.FailureRate
+}
+
+// open trips the breaker. b.mu must be held.
+func (b *CircuitBreaker) open() {
+	b.state = StateOpen
+	b.generation++
+	b.openedAt = b.now()
+}
+
+// close resets the breaker to closed with empty counts. b.mu must be held.
+func (b *CircuitBreaker) close() {
+	b.state = StateClosed
+	b.generation++
+	b.consecutive = 0
+	b.window = window{}
+}
+
+func (b *CircuitBreaker) maxProbes() int {
+	if b.HalfOpenProbes < 1 {
+		return 1
+	}
+	return b.HalfOpenProbes
+}
+
+func (b *CircuitBreaker) now() time.Time {
+	if b.Clock == nil {
+		return RealClock.Now()
+	}
+	return b.Clock.Now()
+}
+
+func (b *CircuitBreaker) notify(from, to BreakerState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(b.Name, from, to)
+	}
+}
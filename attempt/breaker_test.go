@@ -0,0 +1,258 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_ConsecutiveFailures(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	b := NewCircuitBreaker("db", 3, time.Minute)
+	b.Clock = clock
+
+	for i := 0; i < 2; i++ {
+		generation, err := b.Allow()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b.Record(generation, errTransient)
+	}
+	record(b, nil) // a success resets the streak
+	for i := 0; i < 3; i++ {
+		record(b, errTransient)
+	}
+
+	if b.State() != StateOpen {
+		t.Fatalf("expected open, got %v", b.State())
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_FailureRate(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	b := &CircuitBreaker{
+		FailureRate: 0.5,
+		MinCalls:    4,
+		Window:      10 * time.Second,
+		Cooldown:    time.Minute,
+		Clock:       clock,
+	}
+
+	record(b, errTransient)
+	record(b, errTransient)
+	if b.State() != StateClosed {
+		t.Fatalf("tripped before MinCalls")
+	}
+
+	// Failures that left the window no longer count.
+	clock.Advance(10 * time.Second)
+	record(b, nil)
+	record(b, nil)
+	record(b, errTransient)
+	if b.State() != StateClosed {
+		t.Fatalf("tripped on failures outside the window")
+	}
+
+	record(b, errTransient)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open at 50%% failures, got %v", b.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+
+	var (
+		mu          sync.Mutex
+		transitions []string
+	)
+	b := NewCircuitBreaker("api", 1, time.Minute)
+	b.HalfOpenProbes = 2
+	b.Clock = clock
+	b.OnStateChange = func(name string, from, to BreakerState) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, fmt.Sprintf("%s:%v->%v", name, from, to))
+	}
+
+	record(b, errTransient)
+
+	clock.Advance(time.Minute)
+	g1, err1 := b.Allow()
+	g2, err2 := b.Allow()
+	if err1 != nil || err2 != nil {
+		t.Fatalf("expected two probes to be allowed")
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected third probe to be rejected, got %v", err)
+	}
+
+	b.Record(g1, nil)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("closed before every probe succeeded")
+	}
+	b.Record(g2, nil)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %v", b.State())
+	}
+
+	// A failed probe reopens the breaker.
+	record(b, errTransient)
+	clock.Advance(time.Minute)
+	record(b, errTransient)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open after failed probe, got %v", b.State())
+	}
+
+	want := []string{
+		"api:closed->open",
+		"api:open->half-open",
+		"api:half-open->closed",
+		"api:closed->open",
+		"api:open->half-open",
+		"api:half-open->open",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(transitions) != fmt.Sprint(want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+}
+
+func TestCircuitBreaker_IsFailure(t *testing.T) {
+	b := NewCircuitBreaker("cache", 1, time.Minute)
+	b.IsFailure = func(err error) bool {
+		return !errors.Is(err, context.Canceled)
+	}
+
+	record(b, context.Canceled)
+	if b.State() != StateClosed {
+		t.Fatalf("ignored error tripped the breaker")
+	}
+}
+
+func TestCircuitBreaker_Do(t *testing.T) {
+	b := NewCircuitBreaker("svc", 1, time.Hour)
+
+	calls := 0
+	fn := func(ctx context.Context) error {
+		calls++
+		return errTransient
+	}
+	if err := b.Do(context.Background(), fn); !errors.Is(err, errTransient) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Do(context.Background(), fn); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected fail fast, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestCircuitBreaker_StopsRetrier(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	b := NewCircuitBreaker("svc", 2, time.Hour)
+	b.Clock = clock
+
+	r := New(time.Millisecond, time.Millisecond)
+	r.Clock = clock
+	r.Breaker = b
+
+	calls := 0
+	err := Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		return errTransient
+	})
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	if !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, errTransient) {
+		t.Fatalf("expected attempt errors and ErrCircuitOpen, got %v", err)
+	}
+	if r.Stopped() != StoppedByCircuitOpen {
+		t.Fatalf("unexpected stop reason: %v", r.Stopped())
+	}
+
+	// A new sequence fails fast without calling fn.
+	err = Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) || calls != 2 {
+		t.Fatalf("expected fail fast, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCircuitBreaker_StaleRecord(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	b := NewCircuitBreaker("api", 1, time.Minute)
+	b.Clock = clock
+
+	slow, _ := b.Allow()
+	record(b, errTransient)
+	clock.Advance(time.Minute)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open, got %v", b.State())
+	}
+
+	// The call allowed while closed finishes late and must not close the
+	// breaker in place of a probe.
+	b.Record(slow, nil)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("stale success changed the state to %v", b.State())
+	}
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+	b.Record(probe, nil)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %v", b.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenRejectsRetrier(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	b := NewCircuitBreaker("svc", 1, time.Minute)
+	b.Clock = clock
+	record(b, errTransient)
+	clock.Advance(time.Minute)
+	if _, err := b.Allow(); err != nil { // take the only probe
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var reason StopReason
+	r := New(time.Millisecond, time.Millisecond)
+	r.Clock = clock
+	r.Breaker = b
+	r.OnGiveUp = func(attempts int, why StopReason, err error) {
+		reason = why
+	}
+
+	calls := 0
+	err := Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) || calls != 0 {
+		t.Fatalf("expected rejection, got %v after %d calls", err, calls)
+	}
+	if r.Stopped() != StoppedByCircuitOpen || reason != StoppedByCircuitOpen {
+		t.Fatalf("unexpected stop reason: %v, %v", r.Stopped(), reason)
+	}
+}
+
+// record runs a call through b that returns err.
+func record(b *CircuitBreaker, err error) {
+	generation, _ := b.Allow()
+	b.Record(generation, err)
+}
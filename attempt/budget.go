@@ -0,0 +1,105 @@
+package attempt
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryBudget limits retries across every Retrier that shares it, so that a
+// failing dependency does not see its load multiplied by retry storms.
+//
+// Within a sliding Window, retries are allowed while they stay below
+// MinRetries plus Ratio times the successful calls. Successes are recorded by
+// Do, DoValue and Transport, or by calling RecordSuccess directly.
+// It is safe for concurrent use.
+type RetryBudget struct {
+	// Ratio is the fraction of successful calls that may be retried,
+	// e.g. 0.1 allows one retry for every ten successes.
+	Ratio float64
+
+	// MinRetries is allowed per window regardless of traffic, so that
+	// low-volume callers can still retry.
+	MinRetries int
+
+	// Window is the length of the sliding window.
+	Window time.Duration
+
+	// Clock is the source of time for the window. Nil means RealClock.
+	Clock Clock
+
+	mu      sync.Mutex
+	window  window // counts successes and retries
+	allowed uint64
+	denied  uint64
+}
+
+// BudgetStats is a snapshot of a RetryBudget.
+type BudgetStats struct {
+	// Successes and Retries are counted over the current window.
+	Successes, Retries int
+
+	// Allowed and Denied count retry decisions since the budget was created.
+	Allowed, Denied uint64
+}
+
+// NewRetryBudget creates a budget allowing minRetries plus ratio times the
+// successful calls to be retried within each window.
+func NewRetryBudget(ratio float64, minRetries int, window time.Duration) *RetryBudget {
+	return &RetryBudget{
+		Ratio:      ratio,
+		MinRetries: minRetries,
+		Window:     window,
+	}
+}
+
+// RecordSuccess counts a successful call towards the budget.
+func (b *RetryBudget) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window.add(b.now(), b.Window, 1, 0)
+}
+
+// TryRetry reports whether a retry is allowed and, if so, charges it to the
+// budget.
+func (b *RetryBudget) TryRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	successes, retries := b.window.sum(now, b.Window)
+	if float64(retries) >= float64(b.MinRetries)+b.Ratio*float64(successes) {
+		b.denied++
+		return false
+	}
+	b.window.add(now, b.Window, 0, 1)
+	b.allowed++
+	return true
+}
+
+// Stats returns a snapshot of the budget.
+func (b *RetryBudget) Stats() BudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	successes, retries := b.window.sum(b.now(), b.Window)
+	return BudgetStats{
+		Successes: successes,
+		Retries:   retries,
+		Allowed:   b.allowed,
+		Denied:    b.denied,
+	}
+}
+
+func (b *RetryBudget) now() time.Time {
+	if b.Clock == nil {
+		return RealClock.Now()
+	}
+	return b.Clock.Now()
+}
+
+// recordSuccess counts a successful call towards r.Budget, if any.
+func (r *Retrier) recordSuccess() {
+	if r.Budget != nil {
+		r.Budget.RecordSuccess()
+	}
+}
@@ -0,0 +1,118 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget_Ratio(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	b := NewRetryBudget(0.1, 0, 10*time.Second)
+	b.Clock = clock
+
+	if b.TryRetry() {
+		t.Fatalf("retry allowed without successes")
+	}
+	for i := 0; i < 20; i++ {
+		b.RecordSuccess()
+	}
+	if !b.TryRetry() || !b.TryRetry() {
+		t.Fatalf("expected two retries for twenty successes")
+	}
+	if b.TryRetry() {
+		t.Fatalf("retry allowed past the ratio")
+	}
+
+	stats := b.Stats()
+	if stats.Successes != 20 || stats.Retries != 2 || stats.Allowed != 2 || stats.Denied != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRetryBudget_MinRetries(t *testing.T) {
+	b := NewRetryBudget(0, 2, time.Second)
+	b.Clock = NewFakeClock(time.Unix(0, 0))
+
+	if !b.TryRetry() || !b.TryRetry() {
+		t.Fatalf("expected min retries to be allowed")
+	}
+	if b.TryRetry() {
+		t.Fatalf("retry allowed past min retries")
+	}
+}
+
+func TestRetryBudget_SlidingWindow(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	b := NewRetryBudget(0, 1, 10*time.Second)
+	b.Clock = clock
+
+	if !b.TryRetry() {
+		t.Fatalf("expected retry to be allowed")
+	}
+	clock.Advance(5 * time.Second)
+	if b.TryRetry() {
+		t.Fatalf("retry allowed while the first is still in the window")
+	}
+	clock.Advance(5 * time.Second)
+	if !b.TryRetry() {
+		t.Fatalf("expected retry once the first left the window")
+	}
+}
+
+func TestRetryBudget_StopsRetrier(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	b := NewRetryBudget(0, 2, time.Hour)
+	b.Clock = clock
+
+	r := New(time.Millisecond, time.Millisecond)
+	r.Clock = clock
+	r.Budget = b
+
+	calls := 0
+	err := Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		return errTransient
+	})
+	if !errors.Is(err, errTransient) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected first attempt plus two retries, got %d", calls)
+	}
+	if r.Stopped() != StoppedByBudget {
+		t.Fatalf("unexpected stop reason: %v", r.Stopped())
+	}
+	if b.Stats().Denied != 1 {
+		t.Fatalf("expected one denied retry, got %+v", b.Stats())
+	}
+}
+
+func TestRetryBudget_Concurrent(t *testing.T) {
+	b := NewRetryBudget(0.5, 0, time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.RecordSuccess()
+				b.TryRetry()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := b.Stats()
+	if stats.Successes != 800 {
+		t.Fatalf("expected 800 successes, got %d", stats.Successes)
+	}
+	if float64(stats.Retries) > 0.5*float64(stats.Successes) {
+		t.Fatalf("retries exceeded the budget: %+v", stats)
+	}
+}
@@ -0,0 +1,126 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+)
+
+// permanentError marks an error that must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err so that Do and DoValue give up as soon as it is returned.
+// The wrapped error is still reachable through errors.Is and errors.As.
+// Permanent(nil) returns nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether any error in err's chain was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// retryable reports whether err should be followed by another attempt.
+func (r *Retrier) retryable(err error) bool {
+	if IsPermanent(err) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if r.Retryable != nil {
+		return r.Retryable(err)
+	}
+	return true
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error or r stops
+// allowing attempts.
+//
+// r is reset before the first attempt, which runs immediately. An error is
+// not retried when it was marked with Permanent or when r.Retryable returns
+// false for it. Retried errors are passed to r.Observe, so a RetryAfterError
+// hint replaces the computed delay. On failure the returned error joins the
+// error of every attempt, followed by ctx.Err() when the context ended the
+// sequence. When r.Breaker is set every attempt goes through it, and an open
+// breaker ends the sequence with ErrCircuitOpen. fn can inspect the current
+// attempt with AttemptFromContext.
+func Do(ctx context.Context, r *Retrier, fn func(ctx context.Context) error) error {
+	_, err := DoValue(ctx, r, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// DoValue is like Do but returns the value produced by the successful attempt.
+func DoValue[T any](ctx context.Context, r *Retrier, fn func(ctx context.Context) (T, error)) (T, error) {
+	var (
+		zero T
+		errs []error
+	)
+
+	r.Reset()
+	for r.Wait(ctx) {
+		v, err := call(withAttempt(ctx, r), r, fn)
+		if err == nil {
+			r.recordSuccess()
+			return v, nil
+		}
+		errs = append(errs, err)
+		r.Observe(err)
+		if errors.Is(err, ErrCircuitOpen) {
+			r.stop(StoppedByCircuitOpen)
+			return zero, errors.Join(errs...)
+		}
+		if !r.retryable(err) {
+			r.stop(StoppedByPermanentError)
+			return zero, errors.Join(errs...)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	} else if len(errs) == 0 || r.Stopped() == StoppedByCircuitOpen {
+		errs = append(errs, r.stopErr())
+	}
+	return zero, errors.Join(errs...)
+}
+
+// call runs fn through r.Breaker, if any.
+func call[T any](ctx context.Context, r *Retrier, fn func(ctx context.Context) (T, error)) (T, error) {
+	generation, err := r.allow()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	v, err := fn(ctx)
+	r.record(generation, err)
+	return v, err
+}
+
+// allow asks r.Breaker, if any, to let an attempt through.
+func (r *Retrier) allow() (uint64, error) {
+	if r.Breaker == nil {
+		return 0, nil
+	}
+	return r.Breaker.Allow()
+}
+
+// record reports the outcome of an attempt allowed in generation to r.Breaker,
+// if any.
+func (r *Retrier) record(generation uint64, err error) {
+	if r.Breaker != nil {
+		r.Breaker.Record(generation, err)
+	}
+}
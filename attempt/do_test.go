@@ -0,0 +1,144 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func TestDo_SucceedsAfterRetries(t *testing.T) {
+	r := New(time.Millisecond, time.Millisecond)
+
+	calls := 0
+	err := Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestDo_PermanentStops(t *testing.T) {
+	r := New(time.Millisecond, time.Millisecond)
+	errFatal := errors.New("fatal")
+
+	calls := 0
+	err := Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		if calls == 2 {
+			return Permanent(errFatal)
+		}
+		return errTransient
+	})
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	if !errors.Is(err, errFatal) || !errors.Is(err, errTransient) {
+		t.Fatalf("expected joined attempt errors, got %v", err)
+	}
+	if !IsPermanent(err) {
+		t.Fatalf("expected permanent error in chain")
+	}
+}
+
+func TestDo_RetryableClassifier(t *testing.T) {
+	r := New(time.Millisecond, time.Millisecond)
+	r.Retryable = func(err error) bool {
+		return errors.Is(err, errTransient)
+	}
+	errOther := errors.New("other")
+
+	calls := 0
+	err := Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTransient
+		}
+		return errOther
+	})
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	if !errors.Is(err, errOther) {
+		t.Fatalf("expected classified error, got %v", err)
+	}
+}
+
+func TestDo_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := New(time.Millisecond, time.Millisecond)
+
+	calls := 0
+	err := Do(ctx, r, func(ctx context.Context) error {
+		calls++
+		if calls == 2 {
+			cancel()
+		}
+		return errTransient
+	})
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errTransient) {
+		t.Fatalf("expected attempt and context errors, got %v", err)
+	}
+}
+
+func TestDoValue_ReturnsValue(t *testing.T) {
+	r := New(time.Millisecond, time.Millisecond)
+	r.Delay = time.Hour // reset before the first attempt
+
+	calls := 0
+	v, err := DoValue(context.Background(), r, func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errTransient
+		}
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "ok" {
+		t.Fatalf("expected ok, got %q", v)
+	}
+}
+
+func TestPermanent_Nil(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Fatalf("expected nil")
+	}
+	if IsPermanent(errTransient) {
+		t.Fatalf("plain error reported as permanent")
+	}
+}
+
+func TestDo_MaxAttempts(t *testing.T) {
+	r := New(time.Millisecond, time.Millisecond)
+	r.MaxAttempts = 4
+
+	calls := 0
+	err := Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		return errTransient
+	})
+	if calls != 4 {
+		t.Fatalf("expected 4 calls, got %d", calls)
+	}
+	if !errors.Is(err, errTransient) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Stopped() != StoppedByMaxAttempts {
+		t.Fatalf("unexpected stop reason: %v", r.Stopped())
+	}
+}
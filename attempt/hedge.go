@@ -0,0 +1,88 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Hedge calls fn and, if it has not succeeded after delays[0], calls it again
+// concurrently, then again after delays[1], and so on. The first success wins
+// and the contexts of all other attempts are cancelled.
+//
+// An attempt that fails launches the next one right away instead of waiting
+// for its delay. A Permanent error stops hedging. The returned index is 0 for
+// the original call and i for the attempt launched after delays[i-1], or -1
+// when every attempt failed, in which case the error joins all their errors.
+//
+// Hedge returns as soon as the outcome is known. Losing attempts see their
+// context cancelled and their results are discarded, so their goroutines exit
+// as soon as fn returns. fn can read its Attempt number with
+// AttemptFromContext.
+func Hedge[T any](ctx context.Context, delays []time.Duration, fn func(ctx context.Context) (T, error)) (T, int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		v     T
+		err   error
+		index int
+	}
+
+	var (
+		zero     T
+		errs     []error
+		n        = len(delays) + 1
+		results  = make(chan result, n) // buffered so losers never block
+		launched int
+		done     int
+		timer    *time.Timer
+		timerC   <-chan time.Time
+	)
+
+	launch := func() {
+		index := launched
+		launched++
+		actx := context.WithValue(ctx, attemptKey{}, Attempt{Number: index + 1})
+		go func() {
+			v, err := fn(actx)
+			results <- result{v: v, err: err, index: index}
+		}()
+
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, timerC = nil, nil
+		if launched < n {
+			timer = time.NewTimer(delays[launched-1])
+			timerC = timer.C
+		}
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	launch()
+	for {
+		select {
+		case <-timerC:
+			launch()
+		case res := <-results:
+			done++
+			if res.err == nil {
+				return res.v, res.index, nil
+			}
+			errs = append(errs, res.err)
+			if IsPermanent(res.err) || done == n {
+				return zero, -1, errors.Join(errs...)
+			}
+			if done == launched {
+				launch()
+			}
+		case <-ctx.Done():
+			return zero, -1, errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+}
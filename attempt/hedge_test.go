@@ -0,0 +1,108 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHedge_FirstWins(t *testing.T) {
+	calls := 0
+	v, index, err := Hedge(context.Background(), []time.Duration{time.Hour}, func(ctx context.Context) (string, error) {
+		calls++
+		return "fast", nil
+	})
+	if err != nil || v != "fast" || index != 0 {
+		t.Fatalf("unexpected result: %q %d %v", v, index, err)
+	}
+	if calls != 1 {
+		t.Fatalf("hedge launched although the first attempt answered")
+	}
+}
+
+func TestHedge_HedgeWinsAndLosersCancelled(t *testing.T) {
+	var losers sync.WaitGroup
+	losers.Add(1)
+
+	v, index, err := Hedge(context.Background(), []time.Duration{10 * time.Millisecond, 10 * time.Millisecond},
+		func(ctx context.Context) (int, error) {
+			a, _ := AttemptFromContext(ctx)
+			if a.Number == 1 {
+				defer losers.Done()
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return a.Number, nil
+		})
+	if err != nil || v != 2 || index != 1 {
+		t.Fatalf("unexpected result: %d %d %v", v, index, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		losers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("losing attempt was not cancelled")
+	}
+}
+
+func TestHedge_FailureLaunchesNext(t *testing.T) {
+	start := time.Now()
+	v, index, err := Hedge(context.Background(), []time.Duration{time.Hour}, func(ctx context.Context) (string, error) {
+		if a, _ := AttemptFromContext(ctx); a.Number == 1 {
+			return "", errTransient
+		}
+		return "second", nil
+	})
+	if err != nil || v != "second" || index != 1 {
+		t.Fatalf("unexpected result: %q %d %v", v, index, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("waited for the hedge delay after a failure")
+	}
+}
+
+func TestHedge_AllFail(t *testing.T) {
+	_, index, err := Hedge(context.Background(), []time.Duration{time.Millisecond, time.Millisecond},
+		func(ctx context.Context) (int, error) {
+			return 0, errTransient
+		})
+	if index != -1 || !errors.Is(err, errTransient) {
+		t.Fatalf("unexpected result: %d %v", index, err)
+	}
+}
+
+func TestHedge_PermanentStops(t *testing.T) {
+	calls := 0
+	_, _, err := Hedge(context.Background(), []time.Duration{time.Hour}, func(ctx context.Context) (int, error) {
+		calls++
+		return 0, Permanent(errTransient)
+	})
+	if !IsPermanent(err) || calls != 1 {
+		t.Fatalf("expected permanent error after 1 call, got %v after %d", err, calls)
+	}
+}
+
+func TestHedge_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, index, err := Hedge(ctx, []time.Duration{time.Hour}, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		// simulate a callee that is slow to notice cancellation
+		time.Sleep(50 * time.Millisecond)
+		return 0, ctx.Err()
+	})
+	if index != -1 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected result: %d %v", index, err)
+	}
+}
@@ -0,0 +1,93 @@
+package attempt
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryAfterError is implemented by errors that carry a server-provided hint
+// for how long to wait before retrying, such as an HTTP Retry-After header.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+func (e *retryAfterError) RetryAfter() time.Duration {
+	return e.after
+}
+
+// WithRetryAfter wraps err with a hint to wait d before the next attempt.
+// WithRetryAfter(nil, d) returns nil.
+func WithRetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, after: d}
+}
+
+// Observe feeds the error of the last attempt to the Retrier, which passes it
+// on to OnRetry and OnGiveUp.
+// If any error in err's chain implements RetryAfterError with a positive hint,
+// the next Wait sleeps for that hint clamped to Floor and Ceil instead of the
+// computed delay. Do and DoValue call Observe for every retried error.
+func (r *Retrier) Observe(err error) {
+	r.hint = 0
+	r.lastErr = err
+
+	var h RetryAfterError
+	if errors.As(err, &h) {
+		r.hint = h.RetryAfter()
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header value given either as a number
+// of seconds or as an HTTP-date, which is resolved relative to now.
+// It reports false when the value is empty or malformed. Dates in the past
+// yield zero.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return clampDuration(float64(secs) * float64(time.Second)), true
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// ResponseRetryAfter extracts the Retry-After header from res.
+// It reports false when res is nil or carries no valid header.
+func ResponseRetryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	return ParseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+}
@@ -0,0 +1,115 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-5", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResponseRetryAfter(t *testing.T) {
+	if _, ok := ResponseRetryAfter(nil); ok {
+		t.Fatalf("expected no hint for nil response")
+	}
+
+	res := &http.Response{Header: http.Header{}}
+	res.Header.Set("Retry-After", "3")
+	d, ok := ResponseRetryAfter(res)
+	if !ok || d != 3*time.Second {
+		t.Fatalf("expected 3s, got %v, %v", d, ok)
+	}
+}
+
+func TestObserve_HintClamped(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	r := New(time.Second, 10*time.Second)
+	r.Clock = clock
+
+	ctx := context.Background()
+	r.Wait(ctx)
+
+	r.Observe(WithRetryAfter(errTransient, 5*time.Second))
+	r.Wait(ctx)
+	if r.Delay != 5*time.Second {
+		t.Fatalf("expected hinted delay, got %v", r.Delay)
+	}
+
+	r.Observe(WithRetryAfter(errTransient, time.Hour))
+	r.Wait(ctx)
+	if r.Delay != 10*time.Second {
+		t.Fatalf("expected hint clamped to ceil, got %v", r.Delay)
+	}
+
+	r.Observe(WithRetryAfter(errTransient, time.Millisecond))
+	r.Wait(ctx)
+	if r.Delay != time.Second {
+		t.Fatalf("expected hint clamped to floor, got %v", r.Delay)
+	}
+
+	r.Observe(errTransient)
+	r.Wait(ctx)
+	if want := time.Duration(float64(time.Second) * r.Rate); r.Delay != want {
+		t.Fatalf("expected computed delay without hint, got %v", r.Delay)
+	}
+}
+
+func TestDo_HonorsRetryAfter(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	r := New(time.Millisecond, time.Minute)
+	r.Clock = clock
+
+	start := clock.Now()
+	calls := 0
+	err := Do(context.Background(), r, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return WithRetryAfter(errTransient, 30*time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if took := clock.Now().Sub(start); took != 30*time.Second {
+		t.Fatalf("expected to wait 30s, waited %v", took)
+	}
+}
+
+func TestWithRetryAfter_Unwrap(t *testing.T) {
+	if WithRetryAfter(nil, time.Second) != nil {
+		t.Fatalf("expected nil")
+	}
+	err := WithRetryAfter(errTransient, time.Second)
+	if !errors.Is(err, errTransient) {
+		t.Fatalf("wrapped error not reachable")
+	}
+}
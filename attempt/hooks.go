@@ -0,0 +1,50 @@
+package attempt
+
+import (
+	"context"
+	"time"
+)
+
+// Attempt is a snapshot of a Retrier's progress.
+type Attempt struct {
+	// Number is how many attempts have been allowed since the last Reset,
+	// i.e. the number of the attempt in progress.
+	Number int
+
+	// Elapsed is the time since the first call to Wait.
+	Elapsed time.Duration
+
+	// NextDelay is the delay planned before the next attempt, taking any hint
+	// passed to Observe into account. It is zero before the first attempt.
+	NextDelay time.Duration
+
+	// Last reports whether MaxAttempts or MaxElapsed rule out another attempt.
+	// A RetryBudget or CircuitBreaker may still end the sequence earlier.
+	Last bool
+}
+
+// Attempt returns a snapshot of the retrier's progress.
+func (r *Retrier) Attempt() Attempt {
+	a := Attempt{Number: r.attempts}
+	a.NextDelay, _ = r.plannedDelay()
+	if !r.started.IsZero() {
+		a.Elapsed = r.clock().Now().Sub(r.started)
+	}
+	a.Last = (r.MaxAttempts > 0 && r.attempts >= r.MaxAttempts) ||
+		(r.MaxElapsed > 0 && a.Elapsed+a.NextDelay > r.MaxElapsed)
+	return a
+}
+
+type attemptKey struct{}
+
+// withAttempt returns a context carrying r's current Attempt.
+func withAttempt(ctx context.Context, r *Retrier) context.Context {
+	return context.WithValue(ctx, attemptKey{}, r.Attempt())
+}
+
+// AttemptFromContext returns the Attempt stored by Do, DoValue and Hedge.
+// Hedge only sets Number.
+func AttemptFromContext(ctx context.Context) (Attempt, bool) {
+	a, ok := ctx.Value(attemptKey{}).(Attempt)
+	return a, ok
+}
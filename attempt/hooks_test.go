@@ -0,0 +1,115 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHooks_OnRetryAndOnGiveUp(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	type retry struct {
+		attempt int
+		delay   time.Duration
+		err     error
+	}
+	var (
+		retries []retry
+		gaveUp  bool
+	)
+
+	r := New(time.Second, time.Second)
+	r.MaxAttempts = 3
+	r.Clock = clock
+	r.OnRetry = func(attempt int, delay time.Duration, err error) {
+		retries = append(retries, retry{attempt, delay, err})
+	}
+	r.OnGiveUp = func(attempts int, reason StopReason, err error) {
+		gaveUp = true
+		if attempts != 3 || reason != StoppedByMaxAttempts || !errors.Is(err, errTransient) {
+			t.Fatalf("unexpected give up: %d %v %v", attempts, reason, err)
+		}
+	}
+
+	Do(context.Background(), r, func(ctx context.Context) error {
+		return errTransient
+	})
+
+	if len(retries) != 2 {
+		t.Fatalf("expected 2 retries, got %v", retries)
+	}
+	for i, rt := range retries {
+		if rt.attempt != i+2 || rt.delay != time.Second || rt.err != errTransient {
+			t.Fatalf("unexpected retry %d: %+v", i, rt)
+		}
+	}
+	if !gaveUp {
+		t.Fatalf("OnGiveUp not called")
+	}
+}
+
+func TestHooks_GiveUpOnPermanent(t *testing.T) {
+	var reason StopReason
+	r := New(time.Millisecond, time.Millisecond)
+	r.OnGiveUp = func(attempts int, why StopReason, err error) {
+		reason = why
+	}
+
+	Do(context.Background(), r, func(ctx context.Context) error {
+		return Permanent(errTransient)
+	})
+	if reason != StoppedByPermanentError {
+		t.Fatalf("unexpected reason: %v", reason)
+	}
+}
+
+func TestAttempt_Snapshot(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	r := New(time.Second, time.Minute)
+	r.Backoff = Linear{Initial: time.Second, Step: time.Second}
+	r.MaxAttempts = 3
+	r.Clock = clock
+
+	var seen []Attempt
+	Do(context.Background(), r, func(ctx context.Context) error {
+		a, ok := AttemptFromContext(ctx)
+		if !ok {
+			t.Fatalf("attempt missing from context")
+		}
+		seen = append(seen, a)
+		return errTransient
+	})
+
+	want := []Attempt{
+		{Number: 1, Elapsed: 0, NextDelay: time.Second},
+		{Number: 2, Elapsed: time.Second, NextDelay: 2 * time.Second},
+		{Number: 3, Elapsed: 3 * time.Second, NextDelay: 3 * time.Second, Last: true},
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("expected %v, got %v", want, seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("attempt %d: expected %+v, got %+v", i+1, want[i], seen[i])
+		}
+	}
+}
+
+func TestAttempt_NextDelayUsesHint(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	r := New(time.Second, time.Minute)
+	r.Clock = clock
+	r.Wait(context.Background())
+
+	r.Observe(WithRetryAfter(errTransient, 10*time.Second))
+	if a := r.Attempt(); a.NextDelay != 10*time.Second {
+		t.Fatalf("expected hinted delay, got %v", a.NextDelay)
+	}
+}
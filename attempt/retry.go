@@ -1,15 +1,15 @@
 package attempt
 
-package retry
-
 import (
 	"context"
+	"fmt"
 	"math"
 	"math/rand"
 	"time"
 )
 
 // Retrier implements an exponentially backing off retry instance.
+// Set Backoff to use a different strategy.
 // Use New instead of creating this object directly.
 type Retrier struct {
 	// Delay is the current delay between attempts.
@@ -31,6 +31,100 @@ type Retrier struct {
 	//
 	// Jitter can help avoid thundering herds.
 	Jitter float64
+
+	// Backoff, when set, computes the delay before each retry instead of Rate
+	// and Jitter. The result is clamped to Floor and Ceil.
+	Backoff Backoff
+
+	// Retryable classifies errors returned to Do and DoValue.
+	// Returning false stops the sequence. A nil Retryable retries every error
+	// that was not marked with Permanent.
+	Retryable func(err error) bool
+
+	// MaxAttempts bounds how many times Wait returns true.
+	// Zero or a negative value means no limit.
+	MaxAttempts int
+
+	// MaxElapsed bounds how long the sequence may run, measured from the first
+	// call to Wait. Wait returns false instead of sleeping past the budget.
+	// Zero or a negative value means no limit.
+	MaxElapsed time.Duration
+
+	// Budget, when set, is consulted before every retry. Wait returns false
+	// once the shared budget is exhausted.
+	Budget *RetryBudget
+
+	// Breaker, when set, stops the sequence while it is open. Do and DoValue
+	// also route every attempt through it.
+	Breaker *CircuitBreaker
+
+	// OnRetry, when set, is called by Wait right before sleeping ahead of a
+	// retry, with the number of the upcoming attempt, the delay and the last
+	// error passed to Observe.
+	OnRetry func(attempt int, delay time.Duration, err error)
+
+	// OnGiveUp, when set, is called whenever the sequence ends without
+	// success, with the attempts made, the reason and the last error passed
+	// to Observe.
+	OnGiveUp func(attempts int, reason StopReason, err error)
+
+	// Clock is the source of time for delays and MaxElapsed.
+	// Nil means RealClock.
+	Clock Clock
+
+	// Rand is the random source for Jitter and Backoff. Seed it to make delay
+	// sequences reproducible. Nil means a source seeded from the current time.
+	Rand *rand.Rand
+
+	attempts int
+	started  time.Time
+	stopped  StopReason
+	hint     time.Duration
+	lastErr  error
+	planned  time.Duration
+	hasPlan  bool
+}
+
+// StopReason describes why Wait stopped allowing attempts.
+type StopReason int
+
+const (
+	// NotStopped means Wait has not returned false since the last Reset.
+	NotStopped StopReason = iota
+	// StoppedByContext means the context was done.
+	StoppedByContext
+	// StoppedByMaxAttempts means MaxAttempts was reached.
+	StoppedByMaxAttempts
+	// StoppedByMaxElapsed means the next attempt would start after MaxElapsed.
+	StoppedByMaxElapsed
+	// StoppedByBudget means the shared RetryBudget denied the retry.
+	StoppedByBudget
+	// StoppedByCircuitOpen means the Breaker was open.
+	StoppedByCircuitOpen
+	// StoppedByPermanentError means an attempt failed with an error that must
+	// not be retried.
+	StoppedByPermanentError
+)
+
+func (s StopReason) String() string {
+	switch s {
+	case NotStopped:
+		return "not stopped"
+	case StoppedByContext:
+		return "context done"
+	case StoppedByMaxAttempts:
+		return "max attempts reached"
+	case StoppedByMaxElapsed:
+		return "max elapsed time reached"
+	case StoppedByBudget:
+		return "retry budget exhausted"
+	case StoppedByCircuitOpen:
+		return "circuit breaker open"
+	case StoppedByPermanentError:
+		return "permanent error"
+	default:
+		return "unknown"
+	}
 }
 
 // New creates a retrier that exponentially backs off from floor to ceil pauses.
@@ -45,11 +139,11 @@ func New(floor, ceil time.Duration) *Retrier {
 	}
 }
 
-func applyJitter(d time.Duration, jitter float64) time.Duration {
+func applyJitter(d time.Duration, jitter float64, rnd *rand.Rand) time.Duration {
 	if jitter == 0 {
 		return d
 	}
-	d *= time.Duration(1 + jitter*rand.NormFloat64())
+	d *= time.Duration(1 + jitter*rnd.NormFloat64())
 	if d < 0 {
 		return 0
 	}
@@ -58,27 +152,148 @@ func applyJitter(d time.Duration, jitter float64) time.Duration {
 
 // Wait returns after min(Delay*Growth, Ceil) or ctx is cancelled.
 // The first call to Wait will return immediately.
+// Wait returns false once ctx is done, MaxAttempts is reached, Breaker is
+// open, MaxElapsed would be exceeded or Budget denies the retry; Stopped
+// reports which.
 func (r *Retrier) Wait(ctx context.Context) bool {
-	r.Delay = time.Duration(float64(r.Delay) * r.Rate)
+	if ctx.Err() != nil {
+		return r.stop(StoppedByContext)
+	}
+	if r.MaxAttempts > 0 && r.attempts >= r.MaxAttempts {
+		return r.stop(StoppedByMaxAttempts)
+	}
+	if r.Breaker != nil && !r.Breaker.ready() {
+		return r.stop(StoppedByCircuitOpen)
+	}
+	if r.started.IsZero() {
+		r.started = r.clock().Now()
+	}
 
-	r.Delay = applyJitter(r.Delay, r.Jitter)
+	r.Delay = r.upcomingDelay()
+	r.hint, r.hasPlan = 0, false
 
-	if r.Delay > r.Ceil {
-		r.Delay = r.Ceil
+	if r.MaxElapsed > 0 && r.clock().Now().Sub(r.started)+r.Delay > r.MaxElapsed {
+		return r.stop(StoppedByMaxElapsed)
+	}
+	if r.attempts > 0 && r.Budget != nil && !r.Budget.TryRetry() {
+		return r.stop(StoppedByBudget)
+	}
+	if r.attempts > 0 && r.OnRetry != nil {
+		r.OnRetry(r.attempts+1, r.Delay, r.lastErr)
 	}
 
 	select {
-	case <-time.After(r.Delay):
-		if r.Delay < r.Floor {
+	case <-r.clock().After(r.Delay):
+		if r.Backoff == nil && r.Delay < r.Floor {
 			r.Delay = r.Floor
 		}
+		r.attempts++
+		r.planned, r.hasPlan = r.nextDelay(), true
 		return true
 	case <-ctx.Done():
-		return false
+		return r.stop(StoppedByContext)
 	}
 }
 
+// upcomingDelay returns the delay before the next attempt: the hint passed to
+// Observe if any, otherwise the delay planned after the last attempt.
+func (r *Retrier) upcomingDelay() time.Duration {
+	if d, ok := r.plannedDelay(); ok {
+		return d
+	}
+	return r.nextDelay()
+}
+
+// plannedDelay returns the hint passed to Observe clamped to Floor and Ceil,
+// or the delay planned after the last attempt. It reports false when neither
+// is known yet.
+func (r *Retrier) plannedDelay() (time.Duration, bool) {
+	if r.hint > 0 {
+		d := r.hint
+		if d > r.Ceil {
+			d = r.Ceil
+		}
+		if d < r.Floor {
+			d = r.Floor
+		}
+		return d, true
+	}
+	return r.planned, r.hasPlan
+}
+
+// nextDelay computes the delay before the next attempt from Backoff or Rate
+// and Jitter.
+func (r *Retrier) nextDelay() time.Duration {
+	if r.Backoff == nil {
+		d := applyJitter(time.Duration(float64(r.Delay)*r.Rate), r.Jitter, r.random())
+		if d > r.Ceil {
+			d = r.Ceil
+		}
+		return d
+	}
+
+	if r.attempts == 0 {
+		return 0
+	}
+	d := r.Backoff.Next(r.attempts, r.Delay, r.random())
+	if d > r.Ceil {
+		d = r.Ceil
+	}
+	if d < r.Floor {
+		d = r.Floor
+	}
+	return d
+}
+
+// random returns Rand, seeding it on first use.
+func (r *Retrier) random() *rand.Rand {
+	if r.Rand == nil {
+		r.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return r.Rand
+}
+
+func (r *Retrier) clock() Clock {
+	if r.Clock == nil {
+		return RealClock
+	}
+	return r.Clock
+}
+
+// stop ends the sequence for reason and calls OnGiveUp.
+func (r *Retrier) stop(reason StopReason) bool {
+	r.stopped = reason
+	if r.OnGiveUp != nil {
+		r.OnGiveUp(r.attempts, reason, r.lastErr)
+	}
+	return false
+}
+
+// stopErr describes why Wait stopped when no attempt produced an error.
+func (r *Retrier) stopErr() error {
+	if r.stopped == StoppedByCircuitOpen {
+		return ErrCircuitOpen
+	}
+	return fmt.Errorf("attempt: no attempt made: %s", r.stopped)
+}
+
+// Attempts returns how many times Wait has returned true since the last Reset.
+func (r *Retrier) Attempts() int {
+	return r.attempts
+}
+
+// Stopped returns why Wait last returned false, or NotStopped if it has not.
+func (r *Retrier) Stopped() StopReason {
+	return r.stopped
+}
+
 // Reset resets the retrier to its initial state.
 func (r *Retrier) Reset() {
 	r.Delay = 0
-}
\ No newline at end of file
+	r.attempts = 0
+	r.started = time.Time{}
+	r.stopped = NotStopped
+	r.hint = 0
+	r.lastErr = nil
+	r.planned, r.hasPlan = 0, false
+}
@@ -1,9 +1,9 @@
 package attempt
-package retry
 
 import (
 	"context"
 	"math"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -36,18 +36,22 @@ func TestScalesExponentially(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
 	r := New(time.Second, time.Second*10)
 	r.Rate = 2
+	r.Clock = clock
 
-	start := time.Now()
+	start := clock.Now()
 
 	for i := 0; i < 3; i++ {
 		t.Logf("delay: %v", r.Delay)
 		r.Wait(ctx)
-		t.Logf("sinceStart: %v", time.Since(start).Round(time.Second))
+		t.Logf("sinceStart: %v", clock.Now().Sub(start))
 	}
 
-	sinceStart := time.Since(start).Round(time.Second)
+	sinceStart := clock.Now().Sub(start)
 	if sinceStart != time.Second*6 {
 		t.Fatalf("did not scale correctly: %v", sinceStart)
 	}
@@ -65,8 +69,13 @@ func TestReset(t *testing.T) {
 func TestJitter_Normal(t *testing.T) {
 	t.Parallel()
 
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
 	r := New(time.Millisecond, time.Millisecond)
 	r.Jitter = 0.5
+	r.Clock = clock
+	r.Rand = rand.New(rand.NewSource(1))
 
 	var (
 		sum   time.Duration
@@ -74,9 +83,9 @@ func TestJitter_Normal(t *testing.T) {
 		ctx   = context.Background()
 	)
 	for i := 0; i < 1000; i++ {
-		start := time.Now()
+		start := clock.Now()
 		r.Wait(ctx)
-		took := time.Since(start)
+		took := clock.Now().Sub(start)
 		waits = append(waits, (took.Seconds() * 1000))
 		sum += took
 	}
@@ -110,4 +119,119 @@ func stdDev(sample []float64) float64 {
 	variance /= float64(len(sample))
 
 	return math.Sqrt(variance)
-}
\ No newline at end of file
+}
+
+func TestMaxAttempts(t *testing.T) {
+	r := New(time.Millisecond, time.Millisecond)
+	r.MaxAttempts = 3
+
+	ctx := context.Background()
+	n := 0
+	for r.Wait(ctx) {
+		n++
+	}
+	if n != 3 || r.Attempts() != 3 {
+		t.Fatalf("expected 3 attempts, got %d (%d)", n, r.Attempts())
+	}
+	if r.Stopped() != StoppedByMaxAttempts {
+		t.Fatalf("unexpected stop reason: %v", r.Stopped())
+	}
+
+	r.Reset()
+	if r.Attempts() != 0 || r.Stopped() != NotStopped {
+		t.Fatalf("reset did not clear limits state")
+	}
+	if !r.Wait(ctx) {
+		t.Fatalf("attempt not allowed after reset")
+	}
+}
+
+func TestMaxElapsed(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+
+	r := New(10*time.Millisecond, 10*time.Millisecond)
+	r.MaxElapsed = 25 * time.Millisecond
+	r.Clock = clock
+
+	ctx := context.Background()
+	start := clock.Now()
+	for r.Wait(ctx) {
+	}
+	if r.Stopped() != StoppedByMaxElapsed {
+		t.Fatalf("unexpected stop reason: %v", r.Stopped())
+	}
+	if r.Attempts() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", r.Attempts())
+	}
+	if took := clock.Now().Sub(start); took != 20*time.Millisecond {
+		t.Fatalf("slept past the budget: %v", took)
+	}
+}
+
+func TestStoppedByContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := New(time.Millisecond, time.Millisecond)
+	r.MaxAttempts = 5
+	if r.Wait(ctx) {
+		t.Fatalf("attempt allowed even though context cancelled")
+	}
+	if r.Stopped() != StoppedByContext {
+		t.Fatalf("unexpected stop reason: %v", r.Stopped())
+	}
+}
+
+func TestFakeClock_Advance(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+
+	r := New(time.Second, time.Second)
+	r.Clock = clock
+
+	ctx := context.Background()
+	if !r.Wait(ctx) {
+		t.Fatalf("attempt not allowed")
+	}
+
+	done := make(chan bool)
+	go func() {
+		done <- r.Wait(ctx)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second / 2)
+	select {
+	case <-done:
+		t.Fatalf("wait returned before the delay elapsed")
+	default:
+	}
+
+	clock.Advance(time.Second / 2)
+	if !<-done {
+		t.Fatalf("attempt not allowed")
+	}
+	if clock.Waiters() != 0 {
+		t.Fatalf("expected no waiters, got %d", clock.Waiters())
+	}
+}
+
+func TestRand_Reproducible(t *testing.T) {
+	delays := func() []time.Duration {
+		clock := NewFakeClock(time.Unix(0, 0))
+		clock.AutoAdvance = true
+
+		r := New(time.Millisecond, time.Second)
+		r.Backoff = DecorrelatedJitter{Base: time.Millisecond}
+		r.Clock = clock
+		r.Rand = rand.New(rand.NewSource(42))
+
+		var out []time.Duration
+		for i := 0; i < 10 && r.Wait(context.Background()); i++ {
+			out = append(out, r.Delay)
+		}
+		return out
+	}
+
+	assertSequence(t, delays(), delays())
+}
@@ -0,0 +1,246 @@
+package attempt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultRetryStatus lists the status codes Transport retries when
+// RetryStatus is nil.
+var DefaultRetryStatus = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// maxDrainBytes bounds how much of a discarded response body is read so the
+// connection can be reused.
+const maxDrainBytes = 64 << 10
+
+// Transport is an http.RoundTripper that retries idempotent requests on
+// connection errors and on the status codes in RetryStatus.
+//
+// Connection errors are failed dials, refused or reset connections and an
+// io.EOF on a reused connection; other errors, such as TLS failures, are
+// returned at once. Set the Retrier's Retryable to classify errors instead.
+//
+// Each request gets its own Retrier from NewRetrier. Requests with a body are
+// only retried when GetBody is set, which http.NewRequest does for common
+// body types. A Retry-After header on a retried response overrides the next
+// delay. The request context bounds the whole sequence. When the Retrier has
+// a Breaker, every attempt goes through it: connection errors and retried
+// status codes count as failures, and a rejected attempt ends the sequence
+// with ErrCircuitOpen.
+type Transport struct {
+	// Base performs the individual attempts. Nil means http.DefaultTransport.
+	Base http.RoundTripper
+
+	// NewRetrier creates the Retrier for a request. Nil means a Retrier from
+	// 100ms to 5s limited to 3 attempts.
+	NewRetrier func() *Retrier
+
+	// RetryStatus lists the status codes that are retried.
+	// Nil means DefaultRetryStatus.
+	RetryStatus []int
+}
+
+// StatusError reports a response whose status code was retried.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("attempt: retryable status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *Transport) retrier() *Retrier {
+	if t.NewRetrier == nil {
+		r := New(100*time.Millisecond, 5*time.Second)
+		r.MaxAttempts = 3
+		return r
+	}
+	return t.NewRetrier()
+}
+
+func (t *Transport) retryStatus(code int) bool {
+	if t.RetryStatus == nil {
+		return slices.Contains(DefaultRetryStatus, code)
+	}
+	return slices.Contains(t.RetryStatus, code)
+}
+
+// RoundTrip implements http.RoundTripper.
+//
+// When retries are exhausted the last response is returned unread, or the
+// last error if the final attempt failed to get one. Intermediate responses
+// are drained and closed.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isIdempotent(req) || !rewindable(req) {
+		return t.base().RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	r := t.retrier()
+
+	var (
+		last    *http.Response
+		lastErr error
+		sent    bool
+	)
+	defer func() {
+		// Base closes the body of every request it gets, this one included.
+		if !sent && req.Body != nil {
+			_ = req.Body.Close()
+		}
+	}()
+	for r.Wait(ctx) {
+		if last != nil {
+			drain(last)
+			last = nil
+		}
+
+		attemptReq := req
+		if r.Attempts() > 1 {
+			var err error
+			if attemptReq, err = rewind(req); err != nil {
+				return nil, err
+			}
+		}
+
+		generation, err := r.allow()
+		if err != nil {
+			if attemptReq != req && attemptReq.Body != nil {
+				_ = attemptReq.Body.Close()
+			}
+			r.Observe(err)
+			r.stop(StoppedByCircuitOpen)
+			return nil, err
+		}
+
+		var reused atomic.Bool
+		attemptReq = attemptReq.WithContext(httptrace.WithClientTrace(attemptReq.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				reused.Store(info.Reused)
+			},
+		}))
+		sent = true
+		res, err := t.base().RoundTrip(attemptReq)
+		if err != nil {
+			r.record(generation, err)
+			r.Observe(err)
+			if ctx.Err() != nil {
+				r.stop(StoppedByContext)
+				return nil, err
+			}
+			retry := r.retryable(err)
+			if r.Retryable == nil {
+				// without a classifier, only connection errors are retried
+				retry = retry && connError(err, reused.Load())
+			}
+			if !retry {
+				r.stop(StoppedByPermanentError)
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+		if !t.retryStatus(res.StatusCode) {
+			r.record(generation, nil)
+			r.recordSuccess()
+			return res, nil
+		}
+
+		last, lastErr = res, nil
+		var hint error = &StatusError{StatusCode: res.StatusCode}
+		r.record(generation, hint)
+		if d, ok := ResponseRetryAfter(res); ok {
+			hint = WithRetryAfter(hint, d)
+		}
+		r.Observe(hint)
+	}
+
+	if r.Stopped() == StoppedByContext {
+		if last != nil {
+			drain(last)
+		}
+		return nil, ctx.Err()
+	}
+	if last != nil {
+		return last, nil
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, r.stopErr()
+}
+
+// connError reports whether err, returned by an attempt, is a connection
+// error worth retrying. reused tells whether the attempt used a connection
+// from the pool, which the server may have closed in the meantime.
+func connError(err error, reused bool) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return reused && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+// isIdempotent reports whether req may be sent more than once, following the
+// rules net/http applies to its own retries.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	if _, ok := req.Header["X-Idempotency-Key"]; ok {
+		return true
+	}
+	return false
+}
+
+// rewindable reports whether req's body can be sent again.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind clones req with a fresh body.
+func rewind(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("attempt: rewinding request body: %w", err)
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// drain discards a bounded amount of res.Body and closes it.
+func drain(res *http.Response) {
+	_, _ = io.CopyN(io.Discard, res.Body, maxDrainBytes)
+	_ = res.Body.Close()
+}
@@ -0,0 +1,335 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testTransport returns a Transport whose retriers never sleep.
+func testTransport(maxAttempts int) *Transport {
+	return &Transport{
+		NewRetrier: func() *Retrier {
+			clock := NewFakeClock(time.Unix(0, 0))
+			clock.AutoAdvance = true
+
+			r := New(time.Millisecond, time.Second)
+			r.MaxAttempts = maxAttempts
+			r.Clock = clock
+			return r
+		},
+	}
+}
+
+func TestTransport_RetriesStatus(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: testTransport(5)}
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("unexpected response: %d %q", res.StatusCode, body)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("expected 3 calls, got %d", n)
+	}
+}
+
+func TestTransport_ReturnsLastResponse(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, "down")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: testTransport(3)}
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusBadGateway || string(body) != "down" {
+		t.Fatalf("unexpected response: %d %q", res.StatusCode, body)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("expected 3 calls, got %d", n)
+	}
+}
+
+func TestTransport_NonIdempotentNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: testTransport(3)}
+	res, err := client.Post(srv.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected 1 call, got %d", n)
+	}
+}
+
+func TestTransport_RewindsBody(t *testing.T) {
+	var (
+		calls  int32
+		bodies = make(chan string, 3)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- string(b)
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	req.Header.Set("Idempotency-Key", "abc")
+
+	client := &http.Client{Transport: testTransport(3)}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	close(bodies)
+	n := 0
+	for b := range bodies {
+		n++
+		if b != "payload" {
+			t.Fatalf("attempt %d sent body %q", n, b)
+		}
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestTransport_ConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	var attempts int
+	tr := testTransport(3)
+	newRetrier := tr.NewRetrier
+	tr.NewRetrier = func() *Retrier {
+		r := newRetrier()
+		r.Retryable = func(err error) bool {
+			attempts++
+			return true
+		}
+		return r
+	}
+
+	client := &http.Client{Transport: tr}
+	if _, err := client.Get(url); err == nil {
+		t.Fatalf("expected connection error")
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 failed attempts, got %d", attempts)
+	}
+}
+
+func TestTransport_RetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	clock.AutoAdvance = true
+	tr := &Transport{
+		NewRetrier: func() *Retrier {
+			r := New(time.Millisecond, time.Minute)
+			r.Clock = clock
+			return r
+		},
+	}
+
+	res, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if waited := clock.Now().Sub(time.Unix(0, 0)); waited != 7*time.Second {
+		t.Fatalf("expected to wait 7s, waited %v", waited)
+	}
+}
+
+func TestTransport_ContextCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := &Transport{
+		NewRetrier: func() *Retrier {
+			return New(time.Hour, time.Hour)
+		},
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	done := make(chan error, 1)
+	go func() {
+		_, err := tr.RoundTrip(req)
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("round trip did not observe cancellation")
+	}
+}
+
+func TestTransport_Breaker(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	b := NewCircuitBreaker("api", 2, time.Hour)
+	tr := testTransport(5)
+	newRetrier := tr.NewRetrier
+	tr.NewRetrier = func() *Retrier {
+		r := newRetrier()
+		r.Breaker = b
+		return r
+	}
+	client := &http.Client{Transport: tr}
+
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", res.StatusCode)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("expected the breaker to stop after 2 calls, got %d", n)
+	}
+
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("expected no call while open, got %d", n)
+	}
+}
+
+// countingTransport counts the attempts passed on to Base.
+type countingTransport struct {
+	Base  http.RoundTripper
+	calls int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	return t.Base.RoundTrip(req)
+}
+
+type errTransport struct{ err error }
+
+func (t errTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
+
+func TestTransport_DefaultClassifier(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	refused := &countingTransport{Base: http.DefaultTransport}
+	tr := testTransport(3)
+	tr.Base = refused
+	if _, err := (&http.Client{Transport: tr}).Get(url); err == nil {
+		t.Fatalf("expected connection error")
+	}
+	if refused.calls != 3 {
+		t.Fatalf("expected 3 attempts on a refused connection, got %d", refused.calls)
+	}
+
+	tlsErr := &countingTransport{Base: errTransport{err: errors.New("tls: failed to verify certificate")}}
+	tr.Base = tlsErr
+	if _, err := (&http.Client{Transport: tr}).Get(url); err == nil {
+		t.Fatalf("expected TLS error")
+	}
+	if tlsErr.calls != 1 {
+		t.Fatalf("expected the TLS error not to be retried, got %d attempts", tlsErr.calls)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestTransport_ClosesBodyWithoutAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	body := &closeRecorder{Reader: strings.NewReader("payload")}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, "http://example.invalid", body)
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("payload")), nil
+	}
+
+	if _, err := testTransport(3).RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context error, got %v", err)
+	}
+	if !body.closed {
+		t.Fatalf("request body was not closed")
+	}
+}
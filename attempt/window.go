@@ -0,0 +1,56 @@
+package attempt
+
+import "time"
+
+// windowBuckets is the number of slots a sliding window is divided into.
+const windowBuckets = 10
+
+type windowBucket struct {
+	index     int64
+	successes int
+	others    int
+}
+
+// window counts successes and other events, such as retries or failures,
+// over a sliding window of a given length. It is not safe for concurrent use.
+type window struct {
+	buckets [windowBuckets]windowBucket
+}
+
+// add counts successes and others at now, in a window of length length.
+func (w *window) add(now time.Time, length time.Duration, successes, others int) {
+	bucket := w.bucket(now, length)
+	bucket.successes += successes
+	bucket.others += others
+}
+
+// sum totals the events within the window of length length ending at now.
+func (w *window) sum(now time.Time, length time.Duration) (successes, others int) {
+	index := w.bucket(now, length).index
+	for _, bucket := range w.buckets {
+		if bucket.index > index-windowBuckets && bucket.index <= index {
+			successes += bucket.successes
+			others += bucket.others
+		}
+	}
+	return successes, others
+}
+
+// bucket returns the bucket for now, recycling it if it holds stale counts.
+func (w *window) bucket(now time.Time, length time.Duration) *windowBucket {
+	width := int64(length) / windowBuckets
+	if width <= 0 {
+		width = 1
+	}
+	index := now.UnixNano() / width
+
+	slot := index % windowBuckets
+	if slot < 0 {
+		slot += windowBuckets
+	}
+	bucket := &w.buckets[slot]
+	if bucket.index != index {
+		*bucket = windowBucket{index: index}
+	}
+	return bucket
+}
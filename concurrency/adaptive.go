@@ -0,0 +1,191 @@
+package concurrency
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// AdaptiveOption configures a GoPool whose worker cap follows the load.
+//
+// Every Interval, the Controller looks at how long the tasks started during the interval
+// waited in the queue and sets the cap for the next one, within MinWorkers and MaxWorkers.
+// Workers above a lowered cap exit after their current task. The controller runs in its own
+// goroutine until the pool is shut down.
+type AdaptiveOption struct {
+	// MinWorkers and MaxWorkers bound the worker cap. MinWorkers below 1 is treated as 1,
+	// MaxWorkers below MinWorkers as MinWorkers.
+	MinWorkers int
+	MaxWorkers int
+
+	// Interval is how often the cap is adjusted. 0 means 100ms.
+	Interval time.Duration
+
+	// Controller picks the cap. Nil means AIMD with a 10ms target.
+	Controller SizeController
+}
+
+func (o *AdaptiveOption) minWorkers() int {
+	if o.MinWorkers < 1 {
+		return 1
+	}
+	return o.MinWorkers
+}
+
+func (o *AdaptiveOption) maxWorkers() int {
+	if o.MaxWorkers < o.minWorkers() {
+		return o.minWorkers()
+	}
+	return o.MaxWorkers
+}
+
+// SizeSample is what a SizeController gets to decide on the next worker cap.
+type SizeSample struct {
+	// Limit is the current worker cap.
+	Limit int
+
+	// Workers and Busy are the number of workers and how many of them run a task, and
+	// Queued the number of tasks waiting, at the end of the interval.
+	Workers int
+	Busy    int
+	Queued  int
+
+	// Started is the number of tasks started during the interval, and QueueWait their mean
+	// time in the queue.
+	Started   uint64
+	QueueWait time.Duration
+
+	Interval time.Duration
+}
+
+// SizeController picks a pool's worker cap. The result is clamped to the bounds of
+// AdaptiveOption.
+type SizeController interface {
+	NextLimit(s SizeSample) int
+}
+
+// AIMD grows the worker cap by Increase while tasks wait longer than Target, and shrinks
+// it by the factor Decrease while they wait less than half of it with workers to spare.
+// It never shrinks below the number of busy workers.
+type AIMD struct {
+	Target time.Duration
+
+	// Increase is added to the cap. Values below 1 are treated as 1.
+	Increase int
+
+	// Decrease multiplies the cap. Values outside (0, 1) are treated as 0.5.
+	Decrease float64
+}
+
+// NextLimit implements SizeController.
+func (c AIMD) NextLimit(s SizeSample) int {
+	switch {
+	case s.QueueWait > c.Target, s.Started == 0 && s.Queued > 0:
+		inc := c.Increase
+		if inc < 1 {
+			inc = 1
+		}
+		return s.Limit + inc
+	case s.QueueWait < c.Target/2 && s.Queued == 0 && s.Busy < s.Limit:
+		dec := c.Decrease
+		if dec <= 0 || dec >= 1 {
+			dec = 0.5
+		}
+		return max(int(float64(s.Limit)*dec), s.Busy)
+	default:
+		return s.Limit
+	}
+}
+
+// TargetLatency scales the worker cap in proportion to how far the queue wait is from
+// Target, at most doubling or halving it per interval. It never shrinks below the number
+// of busy workers.
+type TargetLatency struct {
+	Target time.Duration
+}
+
+// NextLimit implements SizeController.
+func (c TargetLatency) NextLimit(s SizeSample) int {
+	if s.Started == 0 {
+		if s.Queued > 0 {
+			return 2 * s.Limit // stalled
+		}
+		return max(s.Limit-1, s.Busy)
+	}
+	ratio := float64(s.QueueWait) / float64(max(c.Target, 1))
+	ratio = math.Max(0.5, math.Min(2, ratio))
+	return max(int(math.Ceil(float64(s.Limit)*ratio)), s.Busy)
+}
+
+// runAdaptive adjusts p.maxWorkers every interval until the pool shuts down.
+func (p *GoPool) runAdaptive(o AdaptiveOption) {
+	interval := o.Interval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	controller := o.Controller
+	if controller == nil {
+		controller = AIMD{Target: 10 * time.Millisecond}
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var prevCount uint64
+	var prevSum time.Duration
+	for {
+		select {
+		case <-t.C:
+		case <-p.closing:
+			return
+		}
+
+		wait := p.stats.queueWait.snapshot()
+		sample := SizeSample{
+			Limit:    int(atomic.LoadInt32(&p.maxWorkers)),
+			Workers:  p.CurrentWorkers(),
+			Busy:     int(atomic.LoadInt32(&p.stats.busy)),
+			Queued:   p.queued(),
+			Started:  wait.Count - prevCount,
+			Interval: interval,
+		}
+		if sample.Started > 0 {
+			sample.QueueWait = (wait.Sum - prevSum) / time.Duration(sample.Started)
+		}
+		prevCount, prevSum = wait.Count, wait.Sum
+
+		p.setLimit(controller.NextLimit(sample), o.minWorkers(), o.maxWorkers())
+	}
+}
+
+// setLimit sets the worker cap, clamped to lo and hi. If it grew, it starts workers for
+// queued tasks, and if it shrank, it wakes up idle workers so that the extra ones exit.
+func (p *GoPool) setLimit(limit, lo, hi int) {
+	limit = min(max(limit, lo), hi)
+	old := int(atomic.SwapInt32(&p.maxWorkers, int32(limit)))
+	for i := old; i < limit && p.queued() > 0; i++ {
+		p.spawnWorker()
+	}
+	for i := limit; i < old; i++ {
+		select {
+		case p.queues[PriorityNormal.level()] <- noopTask:
+		default:
+			return
+		}
+	}
+}
+
+// retire reports whether the calling worker should exit because the pool has more workers
+// than its cap, in which case it was already uncounted from p.workers.
+func (p *GoPool) retire() bool {
+	for {
+		limit := atomic.LoadInt32(&p.maxWorkers)
+		n := atomic.LoadInt32(&p.workers)
+		if limit <= 0 || n <= limit {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&p.workers, n, n-1) {
+			return true
+		}
+	}
+}
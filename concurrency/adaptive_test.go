@@ -0,0 +1,68 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAIMD(t *testing.T) {
+	c := AIMD{Target: 10 * time.Millisecond, Increase: 2}
+
+	require.Equal(t, 6, c.NextLimit(SizeSample{Limit: 4, Busy: 4, Started: 10, QueueWait: 20 * time.Millisecond}))
+	require.Equal(t, 6, c.NextLimit(SizeSample{Limit: 4, Busy: 4, Queued: 3}))
+	require.Equal(t, 4, c.NextLimit(SizeSample{Limit: 4, Busy: 4, Started: 10, QueueWait: 7 * time.Millisecond}))
+	require.Equal(t, 4, c.NextLimit(SizeSample{Limit: 8, Busy: 1, Started: 10, QueueWait: time.Millisecond}))
+	require.Equal(t, 5, c.NextLimit(SizeSample{Limit: 8, Busy: 5, Started: 10}))
+	require.Equal(t, 8, c.NextLimit(SizeSample{Limit: 8, Busy: 8, Started: 10}))
+}
+
+func TestTargetLatency(t *testing.T) {
+	c := TargetLatency{Target: 10 * time.Millisecond}
+
+	require.Equal(t, 6, c.NextLimit(SizeSample{Limit: 4, Busy: 4, Started: 10, QueueWait: 15 * time.Millisecond}))
+	require.Equal(t, 8, c.NextLimit(SizeSample{Limit: 4, Busy: 4, Started: 10, QueueWait: time.Second}))
+	require.Equal(t, 4, c.NextLimit(SizeSample{Limit: 8, Busy: 1, Started: 10}))
+	require.Equal(t, 6, c.NextLimit(SizeSample{Limit: 8, Busy: 6, Started: 10}))
+	require.Equal(t, 16, c.NextLimit(SizeSample{Limit: 8, Queued: 1}))
+	require.Equal(t, 7, c.NextLimit(SizeSample{Limit: 8}))
+}
+
+func TestGoPool_Adaptive(t *testing.T) {
+	o := DefaultOption()
+	o.Adaptive = &AdaptiveOption{
+		MinWorkers: 1,
+		MaxWorkers: 8,
+		Interval:   5 * time.Millisecond,
+		Controller: AIMD{Target: time.Millisecond},
+	}
+	p := NewGoPool("TestGoPool_Adaptive", o)
+	require.Equal(t, 1, p.Stats().Limit)
+
+	// a burst grows the cap
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			p.Go(func() { time.Sleep(2 * time.Millisecond) })
+		}
+	}()
+	require.Eventually(t, func() bool { return p.Stats().Limit == 8 }, 5*time.Second, time.Millisecond)
+	require.LessOrEqual(t, p.CurrentWorkers(), 8)
+	close(stop)
+
+	// idling shrinks it back, and the extra workers go away
+	require.Eventually(t, func() bool {
+		s := p.Stats()
+		return s.Limit == 1 && s.Workers <= 1
+	}, 5*time.Second, time.Millisecond)
+
+	_, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+}
@@ -0,0 +1,115 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is the error reported for a task that panicked.
+type PanicError struct {
+	// Value is what recover() returned.
+	Value interface{}
+
+	// Stack is the stack of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("gopool: task panicked: %v\n%s", e.Value, e.Stack)
+}
+
+// Future is the pending result of a task started with Submit.
+type Future[T any] struct {
+	done   chan struct{}
+	once   sync.Once
+	cancel context.CancelFunc
+
+	v   T
+	err error
+}
+
+// Submit runs fn on p and returns a Future for its result.
+//
+// fn gets a ctx derived from ctx that is cancelled by Future.Cancel. If the task panics,
+// the pool's panic handler still runs and the Future fails with a *PanicError, unless
+// PanicRestart runs fn again, in which case the Future gets the result of the last run.
+// If p refuses or drops the task, the Future fails with the error from CtxGo,
+// e.g. ErrPoolFull or ErrPoolClosed, or with the reason it was dropped.
+// A deadline set with WithDeadline or WithTimeout also bounds fn's ctx.
+func Submit[T any](p *GoPool, ctx context.Context, fn func(ctx context.Context) (T, error), opts ...TaskOption) *Future[T] {
+	t := newTask(ctx, nil, opts)
+
+	var cancel context.CancelFunc
+	if t.deadline.IsZero() {
+		t.ctx, cancel = context.WithCancel(ctx)
+	} else {
+		t.ctx, cancel = context.WithDeadline(ctx, t.deadline)
+	}
+	f := &Future[T]{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	tctx := t.ctx
+	t.dropped = func(err error) {
+		var zero T
+		f.complete(zero, err)
+	}
+	// set by the last run that panicked, read once the pool gave up restarting it
+	var perr *PanicError
+	t.gaveUp = func() {
+		var zero T
+		f.complete(zero, perr)
+	}
+	t.f = func() {
+		defer func() {
+			if r := recover(); r != nil {
+				perr = &PanicError{Value: r, Stack: debug.Stack()}
+				// let the pool's panic handler see it too, and maybe restart fn
+				panic(r)
+			}
+		}()
+
+		v, err := fn(tctx)
+		f.complete(v, err)
+	}
+	if err := p.submit(t); err != nil {
+		var zero T
+		f.complete(zero, err)
+	}
+	return f
+}
+
+// complete sets the result unless the Future is already done.
+func (f *Future[T]) complete(v T, err error) {
+	f.once.Do(func() {
+		f.v, f.err = v, err
+		close(f.done)
+		f.cancel()
+	})
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get waits for the result, or returns ctx.Err() if ctx is done first.
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.v, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Cancel cancels the task's context and fails the Future with context.Canceled
+// unless it has already completed. A task that hasn't started yet is skipped.
+func (f *Future[T]) Cancel() {
+	var zero T
+	f.complete(zero, context.Canceled)
+}
@@ -0,0 +1,131 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmit_Result(t *testing.T) {
+	p := NewGoPool("TestSubmit_Result", nil)
+
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	v, err := f.Get(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 42, v)
+
+	select {
+	case <-f.Done():
+	default:
+		t.Fatal("Done not closed after Get")
+	}
+}
+
+func TestSubmit_Error(t *testing.T) {
+	p := NewGoPool("TestSubmit_Error", nil)
+	x := errors.New("x")
+
+	f := Submit(p, context.Background(), func(ctx context.Context) (string, error) {
+		return "", x
+	})
+	_, err := f.Get(context.Background())
+	require.Same(t, x, err)
+}
+
+func TestSubmit_Panic(t *testing.T) {
+	p := NewGoPool("TestSubmit_Panic", nil)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	p.SetPanicHandler(func(ctx context.Context, r interface{}) {
+		defer wg.Done()
+		require.Equal(t, "boom", r)
+	})
+
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		panic("boom")
+	})
+	_, err := f.Get(context.Background())
+
+	var pe *PanicError
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, "boom", pe.Value)
+	require.Contains(t, string(pe.Stack), "TestSubmit_Panic")
+	wg.Wait()
+}
+
+func TestFuture_Cancel(t *testing.T) {
+	p := NewGoPool("TestFuture_Cancel", nil)
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+		return 1, nil
+	})
+	<-started
+	f.Cancel()
+
+	_, err := f.Get(context.Background())
+	require.True(t, errors.Is(err, context.Canceled))
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task context not cancelled")
+	}
+}
+
+func TestFuture_CancelBeforeStart(t *testing.T) {
+	p, release := newBlockedPool(t, 0)
+
+	ran := false
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		ran = true
+		return 1, nil
+	})
+	f.Cancel()
+	_, err := f.Get(context.Background())
+	require.True(t, errors.Is(err, context.Canceled))
+
+	// the skipped task still drains from the queue without calling fn
+	done := make(chan struct{})
+	p.Go(func() { close(done) })
+	close(release)
+	<-done
+	require.False(t, ran)
+}
+
+func TestFuture_GetContext(t *testing.T) {
+	p := NewGoPool("TestFuture_GetContext", nil)
+
+	release := make(chan struct{})
+	defer close(release)
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := f.Get(ctx)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestSubmit_Rejected(t *testing.T) {
+	p, release := newBlockedPool(t, 1, withOverflow(OverflowReject))
+	defer close(release)
+
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+	_, err := f.Get(context.Background())
+	require.True(t, errors.Is(err, ErrPoolFull))
+}
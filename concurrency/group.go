@@ -0,0 +1,148 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// Group runs related tasks on a GoPool and waits for them, like errgroup.Group but reusing
+// the pool's workers instead of spawning goroutines.
+//
+// The first task to fail cancels the group's context, so tasks still queued are skipped and
+// running ones can stop early. A panicking task fails with a *PanicError holding its stack.
+// Wait cancels the group's context too, so a Group can't be reused afterwards.
+type Group struct {
+	pool   *GoPool
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	join   bool
+
+	wg  sync.WaitGroup
+	sem chan struct{}
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewGroup creates a Group running its tasks on pool, or on the default pool if nil.
+// The returned context is cancelled when a task fails or Wait returns, whichever comes first.
+func NewGroup(ctx context.Context, pool *GoPool) (*Group, context.Context) {
+	if pool == nil {
+		pool = defaultGoPool
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{pool: pool, ctx: ctx, cancel: cancel}, ctx
+}
+
+// SetLimit limits the number of tasks in flight, queued or running, to n. Go blocks
+// while the limit is reached. A negative n means no limit.
+//
+// It must not be called while tasks are in flight.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("gopool: modify group limit while %v tasks are in flight", len(g.sem)))
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// SetJoinErrors makes Wait return all task errors joined with errors.Join instead of only
+// the first one. The first error still cancels the group's context.
+func (g *Group) SetJoinErrors(join bool) {
+	g.join = join
+}
+
+// Go runs f on the pool with the group's context. It blocks while the limit set by SetLimit
+// is reached. If the pool refuses the task, its error counts as the task's.
+func (g *Group) Go(f func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(f)
+}
+
+// TryGo is like Go but reports false instead of blocking when the limit is reached.
+func (g *Group) TryGo(f func(ctx context.Context) error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(f)
+	return true
+}
+
+func (g *Group) start(f func(ctx context.Context) error) {
+	g.wg.Add(1)
+
+	t := newTask(g.ctx, nil, nil)
+	t.dropped = g.skipped
+	t.f = func() {
+		g.done(protect(g.ctx, f))
+	}
+	if err := g.pool.submit(t); err != nil {
+		g.done(err)
+	}
+}
+
+// protect calls f, turning a panic into a *PanicError.
+func protect(ctx context.Context, f func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return f(ctx)
+}
+
+// done records the outcome of a task.
+func (g *Group) done(err error) {
+	if err != nil {
+		g.mu.Lock()
+		g.errs = append(g.errs, err)
+		g.mu.Unlock()
+		g.cancel(err)
+	}
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+// skipped records a task the pool dropped, usually because the group's context is done.
+// Unless that was caused by the parent context, another task has already failed and
+// reporting the same cancellation for every skipped task would only add noise.
+func (g *Group) skipped(err error) {
+	g.mu.Lock()
+	if len(g.errs) > 0 {
+		err = nil
+	}
+	g.mu.Unlock()
+	g.done(err)
+}
+
+// Wait waits for all tasks to finish or be skipped, then cancels the group's context and returns the first error, or all of
+// them joined if SetJoinErrors was set.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case len(g.errs) == 0:
+		return nil
+	case g.join && len(g.errs) > 1:
+		return errors.Join(g.errs...)
+	default:
+		return g.errs[0]
+	}
+}
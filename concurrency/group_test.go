@@ -0,0 +1,130 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	g, ctx := NewGroup(context.Background(), NewGoPool("TestGroup", nil))
+
+	var n int32
+	for i := 0; i < 100; i++ {
+		g.Go(func(ctx context.Context) error {
+			atomic.AddInt32(&n, 1)
+			return nil
+		})
+	}
+	require.NoError(t, g.Wait())
+	require.Equal(t, int32(100), atomic.LoadInt32(&n))
+	require.Error(t, ctx.Err()) // cancelled by Wait
+}
+
+func TestGroup_FirstError(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 1
+	g, ctx := NewGroup(context.Background(), NewGoPool("TestGroup_FirstError", o))
+	x := errors.New("x")
+
+	release := make(chan struct{})
+	g.Go(func(ctx context.Context) error {
+		<-release
+		return x
+	})
+	var ran int32
+	for i := 0; i < 10; i++ {
+		g.Go(func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return errors.New("y")
+		})
+	}
+	close(release)
+
+	require.Same(t, x, g.Wait())
+	require.Same(t, x, context.Cause(ctx))
+	require.Zero(t, atomic.LoadInt32(&ran)) // skipped after the first error
+}
+
+func TestGroup_JoinErrors(t *testing.T) {
+	g, _ := NewGroup(context.Background(), NewGoPool("TestGroup_JoinErrors", nil))
+	g.SetJoinErrors(true)
+	x, y := errors.New("x"), errors.New("y")
+
+	started := make(chan struct{})
+	g.Go(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return y
+	})
+	<-started
+	g.Go(func(ctx context.Context) error { return x })
+	err := g.Wait()
+	require.ErrorIs(t, err, x)
+	require.ErrorIs(t, err, y)
+}
+
+func TestGroup_Panic(t *testing.T) {
+	g, _ := NewGroup(context.Background(), NewGoPool("TestGroup_Panic", nil))
+	g.Go(func(ctx context.Context) error { panic("boom") })
+
+	var pe *PanicError
+	require.True(t, errors.As(g.Wait(), &pe))
+	require.Equal(t, "boom", pe.Value)
+	require.Contains(t, string(pe.Stack), "TestGroup_Panic")
+}
+
+func TestGroup_SetLimit(t *testing.T) {
+	g, _ := NewGroup(context.Background(), NewGoPool("TestGroup_SetLimit", nil))
+	g.SetLimit(2)
+
+	var running, peak int32
+	for i := 0; i < 20; i++ {
+		g.Go(func(ctx context.Context) error {
+			cur := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if cur <= old || atomic.CompareAndSwapInt32(&peak, old, cur) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+	}
+	require.NoError(t, g.Wait())
+	require.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+
+	g, _ = NewGroup(context.Background(), NewGoPool("TestGroup_SetLimit", nil))
+	g.SetLimit(2)
+	release := make(chan struct{})
+	require.True(t, g.TryGo(func(ctx context.Context) error { <-release; return nil }))
+	require.True(t, g.TryGo(func(ctx context.Context) error { <-release; return nil }))
+	require.False(t, g.TryGo(func(ctx context.Context) error { return nil }))
+	require.Panics(t, func() { g.SetLimit(3) })
+	close(release)
+	require.NoError(t, g.Wait())
+}
+
+func TestGroup_ParentCancelled(t *testing.T) {
+	p := NewGoPool("TestGroup_ParentCancelled", nil)
+	p.ShutdownNow()
+
+	g, _ := NewGroup(context.Background(), p)
+	g.Go(func(ctx context.Context) error { return nil })
+	require.ErrorIs(t, g.Wait(), ErrPoolClosed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g, _ = NewGroup(ctx, NewGoPool("TestGroup_ParentCancelled", nil))
+	g.SetJoinErrors(true)
+	for i := 0; i < 3; i++ {
+		g.Go(func(ctx context.Context) error { return nil })
+	}
+	require.Equal(t, context.Canceled, g.Wait())
+}
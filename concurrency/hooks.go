@@ -0,0 +1,68 @@
+package concurrency
+
+import "context"
+
+// ContextHook carries goroutine-local state, such as a session or trace bound to the
+// goroutine, from the goroutine submitting a task to the worker running it.
+//
+// Workers are reused, so a hook must undo in Clear whatever Restore set up, lest it leaks
+// into the next task run by the same worker. A task may also run on a goroutine that has
+// state of its own, e.g. the submitting one with OverflowCallerRuns: the pool captures it
+// with context.Background() right before Restore and, unless it's nil, restores it after
+// Clear.
+type ContextHook interface {
+	// Capture is called on the submitting goroutine and returns the state to carry over.
+	Capture(ctx context.Context) interface{}
+
+	// Restore is called on the worker with the captured state right before the task runs.
+	Restore(ctx context.Context, state interface{})
+
+	// Clear is called on the worker after the task ran, even if it panicked.
+	Clear(ctx context.Context, state interface{})
+}
+
+// SetContextHooks sets the hooks of the default pool, see (*GoPool).SetContextHooks.
+func SetContextHooks(hooks ...ContextHook) {
+	defaultGoPool.SetContextHooks(hooks...)
+}
+
+// SetContextHooks replaces the pool's ContextHooks. Restore runs them in order and Clear in
+// reverse order.
+//
+// Like SetPanicHandler, it's meant to be called before the pool is used.
+func (p *GoPool) SetContextHooks(hooks ...ContextHook) {
+	p.hooks = hooks
+}
+
+// capture returns the states of the pool's hooks for a task submitted with ctx.
+func (p *GoPool) capture(ctx context.Context) []interface{} {
+	if len(p.hooks) == 0 {
+		return nil
+	}
+	states := make([]interface{}, len(p.hooks))
+	for i, h := range p.hooks {
+		states[i] = h.Capture(ctx)
+	}
+	return states
+}
+
+// restore runs the pool's hooks before a task, and returns a func to run after it.
+func (p *GoPool) restore(ctx context.Context, states []interface{}) (cleanup func()) {
+	if len(states) == 0 {
+		return func() {}
+	}
+	hooks := p.hooks[:len(states)]
+	prev := make([]interface{}, len(hooks))
+	for i, h := range hooks {
+		prev[i] = h.Capture(context.Background())
+		h.Restore(ctx, states[i])
+	}
+	return func() {
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hooks[i].Clear(ctx, states[i])
+			if prev[i] != nil {
+				hooks[i].Restore(ctx, prev[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+// recordingHook carries the value of ctxKey and records the calls it gets.
+type recordingHook struct {
+	name string
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (h *recordingHook) Capture(ctx context.Context) interface{} {
+	return ctx.Value(ctxKey{})
+}
+
+func (h *recordingHook) Restore(ctx context.Context, state interface{}) {
+	h.record("restore", state)
+}
+
+func (h *recordingHook) Clear(ctx context.Context, state interface{}) {
+	h.record("clear", state)
+}
+
+func (h *recordingHook) record(call string, state interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, fmt.Sprintf("%s %s %v", h.name, call, state))
+}
+
+func TestGoPool_ContextHooks(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	log := func(s string) {
+		mu.Lock()
+		calls = append(calls, s)
+		mu.Unlock()
+	}
+	a, b := &recordingHook{name: "a"}, &recordingHook{name: "b"}
+
+	p := NewGoPool("TestGoPool_ContextHooks", nil)
+	p.SetContextHooks(a, b)
+	p.SetPanicHandler(func(ctx context.Context, r interface{}) { log("panic") })
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "x")
+	f := Submit(p, ctx, func(ctx context.Context) (int, error) {
+		log("run")
+		panic("boom")
+	})
+	_, err := f.Get(context.Background())
+	require.Error(t, err)
+	_, err = p.Shutdown(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"run", "panic"}, calls)
+	require.Equal(t, []string{"a restore x", "a clear x"}, a.calls)
+	require.Equal(t, []string{"b restore x", "b clear x"}, b.calls)
+}
+
+func TestKeyedPool_ContextHooks(t *testing.T) {
+	h := &recordingHook{name: "h"}
+	o := DefaultOption()
+	o.ContextHooks = []ContextHook{h}
+	k := NewKeyedPool(NewGoPool("TestKeyedPool_ContextHooks", o), 0)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	require.NoError(t, k.CtxGoKey(context.WithValue(context.Background(), ctxKey{}, "1"), "k", wg.Done))
+	require.NoError(t, k.CtxGoKey(context.WithValue(context.Background(), ctxKey{}, "2"), "k", wg.Done))
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	require.Contains(t, h.calls, "h restore 2")
+	require.Contains(t, h.calls, "h clear 2")
+}
@@ -0,0 +1,163 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrKeyQueueFull is returned by GoKey when the key already has the maximum number of tasks queued.
+var ErrKeyQueueFull = errors.New("gopool: key queue is full")
+
+// keyedBatch is how many tasks of a key run back to back on a worker before the rest are
+// handed back to the pool, so that a busy key can't keep a worker to itself.
+const keyedBatch = 32
+
+// KeyedPool runs tasks on a GoPool so that tasks for the same key run one at a time, in the
+// order they were submitted, while tasks for different keys run in parallel.
+//
+// Keys are forgotten as soon as their queue drains, so idle keys cost nothing.
+type KeyedPool struct {
+	pool     *GoPool
+	maxQueue int
+
+	mu   sync.Mutex
+	keys map[string]*keyQueue
+}
+
+type keyedTask struct {
+	ctx       context.Context
+	f         func()
+	submitted time.Time
+	states    []interface{}
+}
+
+type keyQueue struct {
+	tasks   []keyedTask
+	running bool // a runner is scheduled or running for the key
+}
+
+// NewKeyedPool creates a KeyedPool running its tasks on pool, or on the default pool if nil.
+// maxQueuePerKey bounds how many tasks may wait for each key, not counting the running one.
+// 0 means no bound.
+func NewKeyedPool(pool *GoPool, maxQueuePerKey int) *KeyedPool {
+	if pool == nil {
+		pool = defaultGoPool
+	}
+	return &KeyedPool{
+		pool:     pool,
+		maxQueue: maxQueuePerKey,
+		keys:     make(map[string]*keyQueue),
+	}
+}
+
+// GoKey runs f after all tasks submitted earlier for key have finished.
+func (k *KeyedPool) GoKey(key string, f func()) error {
+	return k.CtxGoKey(context.Background(), key, f)
+}
+
+// CtxGoKey is GoKey with a ctx passed to the panic handler. The task is skipped if ctx is done
+// by the time it's its turn.
+//
+// It returns ErrKeyQueueFull if key already has its maximum of tasks queued, or the error of
+// the GoPool if the key's runner could not be scheduled.
+func (k *KeyedPool) CtxGoKey(ctx context.Context, key string, f func()) error {
+	states := k.pool.capture(ctx)
+
+	k.mu.Lock()
+	q := k.keys[key]
+	if q == nil {
+		q = &keyQueue{}
+		k.keys[key] = q
+	}
+	if k.maxQueue > 0 && len(q.tasks) >= k.maxQueue {
+		k.mu.Unlock()
+		return ErrKeyQueueFull
+	}
+	q.tasks = append(q.tasks, keyedTask{ctx: ctx, f: f, submitted: time.Now(), states: states})
+	if q.running {
+		k.mu.Unlock()
+		return nil
+	}
+	q.running = true
+	k.mu.Unlock()
+
+	// not holding k.mu: the pool may block with OverflowBlock
+	err := k.pool.submit(k.runner(key, q))
+	if err == nil {
+		return nil
+	}
+
+	// f was first in line, tasks queued behind it in the meantime have no runner either
+	for i, t := range k.abandon(key, q) {
+		if i > 0 {
+			k.pool.reportDropped(t.ctx, err)
+		}
+	}
+	return err
+}
+
+// Keys returns the number of keys with tasks queued or running.
+func (k *KeyedPool) Keys() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.keys)
+}
+
+// runner returns the pool task running the tasks queued for key.
+// If the pool drops it, e.g. on ShutdownNow, the queued tasks are dropped with it.
+func (k *KeyedPool) runner(key string, q *keyQueue) task {
+	t := newTask(context.Background(), func() { k.run(key, q) }, nil)
+	t.internal = true
+	t.dropped = func(err error) {
+		for _, t := range k.abandon(key, q) {
+			k.pool.reportDropped(t.ctx, err)
+		}
+	}
+	return t
+}
+
+// abandon forgets key, whose runner won't run, and returns the tasks that were queued.
+func (k *KeyedPool) abandon(key string, q *keyQueue) []keyedTask {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	tasks := q.tasks
+	q.tasks, q.running = nil, false
+	delete(k.keys, key)
+	return tasks
+}
+
+// run runs the tasks queued for key until there are none left.
+func (k *KeyedPool) run(key string, q *keyQueue) {
+	for i := 0; ; i++ {
+		k.mu.Lock()
+		if len(q.tasks) == 0 {
+			q.running = false
+			delete(k.keys, key)
+			k.mu.Unlock()
+			return
+		}
+		if i >= keyedBatch && k.pool.trySubmit(k.runner(key, q)) {
+			// the rest runs later, behind other keys' tasks
+			k.mu.Unlock()
+			return
+		}
+		t := q.tasks[0]
+		q.tasks[0] = keyedTask{}
+		q.tasks = q.tasks[1:]
+		k.mu.Unlock()
+
+		if err := t.ctx.Err(); err != nil {
+			k.pool.reportDropped(t.ctx, err)
+			continue
+		}
+		if err := k.pool.throttle(t.ctx); err != nil {
+			k.pool.reportDropped(t.ctx, err)
+			continue
+		}
+		cleanup := k.pool.restore(t.ctx, t.states)
+		k.pool.supervise(&task{ctx: t.ctx, f: t.f, submitted: t.submitted, label: key})
+		cleanup()
+	}
+}
@@ -0,0 +1,171 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedPool_Order(t *testing.T) {
+	k := NewKeyedPool(NewGoPool("TestKeyedPool_Order", nil), 0)
+
+	var (
+		mu      sync.Mutex
+		seen    = map[string][]int{}
+		running = map[string]*int32{}
+		wg      sync.WaitGroup
+	)
+	keys, n := 10, 100
+	for i := 0; i < keys; i++ {
+		running[fmt.Sprint(i)] = new(int32)
+	}
+	wg.Add(keys * n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < keys; i++ {
+			key, j := fmt.Sprint(i), j
+			require.NoError(t, k.GoKey(key, func() {
+				defer wg.Done()
+				require.Equal(t, int32(1), atomic.AddInt32(running[key], 1))
+				defer atomic.AddInt32(running[key], -1)
+
+				mu.Lock()
+				seen[key] = append(seen[key], j)
+				mu.Unlock()
+			}))
+		}
+	}
+	wg.Wait()
+
+	for i := 0; i < keys; i++ {
+		s := seen[fmt.Sprint(i)]
+		require.Len(t, s, n)
+		for j := range s {
+			require.Equal(t, j, s[j])
+		}
+	}
+	require.Eventually(t, func() bool { return k.Keys() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestKeyedPool_Parallel(t *testing.T) {
+	k := NewKeyedPool(NewGoPool("TestKeyedPool_Parallel", nil), 0)
+
+	a, b := make(chan struct{}), make(chan struct{})
+	require.NoError(t, k.GoKey("a", func() {
+		close(a)
+		<-b
+	}))
+	require.NoError(t, k.GoKey("b", func() {
+		<-a
+		close(b)
+	}))
+
+	select {
+	case <-b:
+	case <-time.After(5 * time.Second):
+		t.Fatal("keys did not run in parallel")
+	}
+}
+
+func TestKeyedPool_QueueFull(t *testing.T) {
+	k := NewKeyedPool(NewGoPool("TestKeyedPool_QueueFull", nil), 2)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	require.NoError(t, k.GoKey("a", func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	require.NoError(t, k.GoKey("a", func() {}))
+	require.NoError(t, k.GoKey("a", func() {}))
+	require.True(t, errors.Is(k.GoKey("a", func() {}), ErrKeyQueueFull))
+	require.NoError(t, k.GoKey("b", func() {}))
+	close(release)
+
+	require.Eventually(t, func() bool { return k.Keys() == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, k.GoKey("a", func() {}))
+}
+
+func TestKeyedPool_PanicAndCancel(t *testing.T) {
+	p := NewGoPool("TestKeyedPool_PanicAndCancel", nil)
+	p.SetPanicHandler(func(ctx context.Context, r interface{}) {})
+	k := NewKeyedPool(p, 0)
+
+	release := make(chan struct{})
+	require.NoError(t, k.GoKey("a", func() {
+		<-release
+		panic("boom")
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	var skipped int32
+	require.NoError(t, k.CtxGoKey(ctx, "a", func() { atomic.StoreInt32(&skipped, 1) }))
+	cancel()
+	done := make(chan struct{})
+	require.NoError(t, k.GoKey("a", func() { close(done) }))
+	close(release)
+
+	<-done
+	require.Zero(t, atomic.LoadInt32(&skipped))
+	require.Equal(t, uint64(1), p.Stats().Dropped)
+}
+
+func TestKeyedPool_Closed(t *testing.T) {
+	p := NewGoPool("TestKeyedPool_Closed", nil)
+	k := NewKeyedPool(p, 0)
+	p.ShutdownNow()
+
+	require.True(t, errors.Is(k.GoKey("a", func() {}), ErrPoolClosed))
+	require.Zero(t, k.Keys())
+}
+
+func TestKeyedPool_Batch(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 1
+	k := NewKeyedPool(NewGoPool("TestKeyedPool_Batch", o), 0)
+
+	release := make(chan struct{})
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	record := func(key string) func() {
+		return func() {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, key)
+			mu.Unlock()
+		}
+	}
+	wg.Add(2*keyedBatch + 1)
+	require.NoError(t, k.GoKey("a", func() { <-release }))
+	for i := 0; i < 2*keyedBatch; i++ {
+		require.NoError(t, k.GoKey("a", record("a")))
+	}
+	require.NoError(t, k.GoKey("b", record("b")))
+	close(release)
+	wg.Wait()
+
+	// b gets its turn once a has used up a batch
+	require.Equal(t, "b", order[keyedBatch-1])
+}
+
+func TestKeyedPool_RunnerDropped(t *testing.T) {
+	dropped := &droppedRecorder{}
+	p, release := newBlockedPool(t, 0, withDropped(dropped))
+	defer close(release)
+	k := NewKeyedPool(p, 0)
+
+	require.NoError(t, k.GoKey("a", func() {}))
+	require.NoError(t, k.GoKey("a", func() {}))
+	p.ShutdownNow()
+
+	require.Zero(t, k.Keys())
+	require.Equal(t, []error{ErrPoolClosed, ErrPoolClosed}, dropped.get())
+}
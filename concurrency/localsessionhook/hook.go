@@ -0,0 +1,44 @@
+// Package localsessionhook carries localsession sessions into GoPool tasks.
+//
+// Install it on a pool with
+//
+//	pool.SetContextHooks(localsessionhook.Hook)
+//
+// and the session bound with localsession.BindSession on the goroutine calling CtxGo is
+// bound on the worker while the task runs, as localsession.Go does for new goroutines.
+package localsessionhook
+
+import (
+	"context"
+
+	"github.com/cloudwego/localsession"
+
+	"github.com/mateothegreat/util/concurrency"
+)
+
+// Hook is the concurrency.ContextHook for the default localsession manager, which must be
+// initialized with localsession.InitDefaultManager.
+var Hook concurrency.ContextHook = hook{}
+
+type hook struct{}
+
+// Capture returns the session of the submitting goroutine, if any.
+func (hook) Capture(ctx context.Context) interface{} {
+	s, ok := localsession.CurSession()
+	if !ok {
+		return nil
+	}
+	return s
+}
+
+// Restore binds the captured session to the worker.
+func (hook) Restore(ctx context.Context, state interface{}) {
+	if s, ok := state.(localsession.Session); ok {
+		localsession.BindSession(s)
+	}
+}
+
+// Clear unbinds the worker's session, including one the task bound itself.
+func (hook) Clear(ctx context.Context, state interface{}) {
+	localsession.UnbindSession()
+}
@@ -0,0 +1,113 @@
+package localsessionhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/localsession"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mateothegreat/util/concurrency"
+)
+
+func TestHook(t *testing.T) {
+	localsession.InitDefaultManager(localsession.DefaultManagerOptions())
+
+	o := concurrency.DefaultOption()
+	o.MaxWorkers = 1
+	o.ContextHooks = []concurrency.ContextHook{Hook}
+	p := concurrency.NewGoPool("TestHook", o)
+
+	get := func() interface{} {
+		s, ok := localsession.CurSession()
+		if !ok {
+			return nil
+		}
+		return s.Get("traceID")
+	}
+	run := func() (interface{}, error) {
+		got := make(chan interface{}, 1)
+		if err := p.Go(func() { got <- get() }); err != nil {
+			return nil, err
+		}
+		return <-got, nil
+	}
+
+	// the session is bound on a goroutine of its own, the results are checked here
+	var (
+		bound, unbound       interface{}
+		boundErr, unboundErr error
+		leakErr              error
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer localsession.UnbindSession()
+
+		localsession.BindSession(localsession.NewSessionMap(map[interface{}]interface{}{"traceID": "trace-1"}))
+		bound, boundErr = run()
+
+		// a session the task binds itself doesn't leak into the next task
+		leakErr = p.Go(func() {
+			localsession.BindSession(localsession.NewSessionMap(map[interface{}]interface{}{"traceID": "leaked"}))
+		})
+		localsession.UnbindSession()
+		unbound, unboundErr = run()
+	}()
+	<-done
+
+	require.NoError(t, boundErr)
+	require.Equal(t, "trace-1", bound)
+	require.NoError(t, leakErr)
+	require.NoError(t, unboundErr)
+	require.Nil(t, unbound)
+
+	_, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+}
+
+func TestHookCallerRuns(t *testing.T) {
+	localsession.InitDefaultManager(localsession.DefaultManagerOptions())
+
+	o := concurrency.DefaultOption()
+	o.MaxWorkers = 1
+	o.TaskChanBuffer = 1
+	o.Overflow = concurrency.OverflowCallerRuns
+	o.ContextHooks = []concurrency.ContextHook{Hook}
+	p := concurrency.NewGoPool("TestHookCallerRuns", o)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	require.NoError(t, p.Go(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	require.NoError(t, p.Go(func() { <-release })) // fills the queue
+
+	var (
+		inTask, after interface{}
+		err           error
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer localsession.UnbindSession()
+
+		localsession.BindSession(localsession.NewSessionMap(map[interface{}]interface{}{"traceID": "caller"}))
+		// runs inline, on this goroutine
+		err = p.Go(func() {
+			s, _ := localsession.CurSession()
+			inTask = s.Get("traceID")
+		})
+		if s, ok := localsession.CurSession(); ok {
+			after = s.Get("traceID")
+		}
+	}()
+	<-done
+	close(release)
+
+	require.NoError(t, err)
+	require.Equal(t, "caller", inTask)
+	require.Equal(t, "caller", after)
+}
@@ -0,0 +1,98 @@
+package concurrency
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrPoolFull is returned when a task is rejected because the pool's queue is full.
+var ErrPoolFull = errors.New("gopool: pool is full")
+
+// OverflowPolicy decides what CtxGo does when the task queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowSpawn runs the task on a new goroutine outside the pool. It's the default,
+	// and it's not bounded: under load it creates as many goroutines as there are tasks.
+	OverflowSpawn OverflowPolicy = iota
+
+	// OverflowBlock waits until the queue has room or the task's ctx is done.
+	OverflowBlock
+
+	// OverflowReject returns ErrPoolFull.
+	OverflowReject
+
+	// OverflowDropOldest discards the oldest queued task to make room.
+	OverflowDropOldest
+
+	// OverflowCallerRuns runs the task on the caller's goroutine.
+	OverflowCallerRuns
+)
+
+func (o OverflowPolicy) String() string {
+	switch o {
+	case OverflowSpawn:
+		return "spawn"
+	case OverflowBlock:
+		return "block"
+	case OverflowReject:
+		return "reject"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowCallerRuns:
+		return "caller-runs"
+	default:
+		return "unknown"
+	}
+}
+
+// handleOverflow applies the pool's OverflowPolicy to t after its queue was found full.
+// The policy only looks at the queue of t's priority level.
+// It reports whether t ended up in the queue. If not, and err is nil, runOverflow must run t.
+func (p *GoPool) handleOverflow(t task) (queued bool, err error) {
+	q := p.queues[t.priority.level()]
+	switch p.overflow {
+	case OverflowBlock:
+		// make sure someone is draining the queue while we wait
+		p.spawnWorker()
+		select {
+		case q <- t:
+			return true, nil
+		case <-t.ctx.Done():
+			return false, t.ctx.Err()
+		case <-p.closing:
+			return false, ErrPoolClosed
+		}
+
+	case OverflowReject:
+		return false, ErrPoolFull
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case q <- t:
+				return true, nil
+			default:
+			}
+			select {
+			case old := <-q:
+				p.dropTask(old, ErrPoolFull)
+			default:
+			}
+		}
+
+	default:
+		return false, nil
+	}
+}
+
+// runOverflow runs t outside the queue as OverflowSpawn or OverflowCallerRuns dictate.
+func (p *GoPool) runOverflow(t task) {
+	if p.overflow == OverflowCallerRuns {
+		p.run(t)
+		return
+	}
+	// full? fall back to use go directly
+	atomic.AddUint64(&p.stats.overflowed, 1)
+	go p.run(t)
+}
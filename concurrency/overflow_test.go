@@ -0,0 +1,147 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newBlockedPool returns a pool with a single worker, busy until release is closed.
+// If queue > 0, the task queue holds queue tasks and is filled with tasks that also wait
+// for release. opts adjust the Option further.
+func newBlockedPool(t *testing.T, queue int, opts ...func(o *Option)) (p *GoPool, release chan struct{}) {
+	o := DefaultOption()
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.MaxWorkers = 1
+	if queue > 0 {
+		o.TaskChanBuffer = queue
+	}
+	p = NewGoPool(t.Name(), o)
+
+	release = make(chan struct{})
+	started := make(chan struct{})
+	require.NoError(t, p.Go(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	for i := 0; i < queue; i++ {
+		require.True(t, p.TryGo(func() { <-release }))
+	}
+	return p, release
+}
+
+func withOverflow(policy OverflowPolicy) func(o *Option) {
+	return func(o *Option) { o.Overflow = policy }
+}
+
+func withDropped(r *droppedRecorder) func(o *Option) {
+	return func(o *Option) { o.OnDropped = r.record }
+}
+
+func TestGoPool_MaxWorkers(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 3
+	o.TaskChanBuffer = 1
+	p := NewGoPool("TestGoPool_MaxWorkers", o)
+
+	var (
+		running, peak int32
+		wg            sync.WaitGroup
+	)
+	n := 100
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		require.NoError(t, p.Go(func() {
+			defer wg.Done()
+			cur := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if cur <= old || atomic.CompareAndSwapInt32(&peak, old, cur) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}))
+	}
+	wg.Wait()
+	require.LessOrEqual(t, atomic.LoadInt32(&peak), int32(3))
+	require.LessOrEqual(t, p.CurrentWorkers(), 3)
+}
+
+func TestGoPool_OverflowReject(t *testing.T) {
+	p, release := newBlockedPool(t, 1, withOverflow(OverflowReject))
+	defer close(release)
+
+	err := p.Go(func() {})
+	require.True(t, errors.Is(err, ErrPoolFull))
+}
+
+func TestGoPool_OverflowBlock(t *testing.T) {
+	p, release := newBlockedPool(t, 1, withOverflow(OverflowBlock))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := p.CtxGo(ctx, func() {})
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	done := make(chan struct{})
+	go func() {
+		require.NoError(t, p.Go(func() { close(done) }))
+	}()
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked task never ran")
+	}
+}
+
+func TestGoPool_OverflowDropOldest(t *testing.T) {
+	p, release := newBlockedPool(t, 1, withOverflow(OverflowDropOldest))
+
+	// the queued task is dropped in favour of the new one
+	done := make(chan struct{})
+	require.NoError(t, p.Go(func() { close(done) }))
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("newest task never ran")
+	}
+}
+
+func TestGoPool_OverflowCallerRuns(t *testing.T) {
+	p, release := newBlockedPool(t, 1, withOverflow(OverflowCallerRuns))
+	defer close(release)
+
+	ran := false
+	require.NoError(t, p.Go(func() { ran = true }))
+	require.True(t, ran)
+}
+
+func TestGoPool_OverflowSpawnWithCap(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 1
+	p := NewGoPool("TestGoPool_OverflowSpawnWithCap", o)
+	require.Equal(t, OverflowBlock, p.overflow)
+}
+
+func TestGoPool_TryGo(t *testing.T) {
+	p, release := newBlockedPool(t, 1, withOverflow(OverflowBlock))
+
+	require.False(t, p.TryGo(func() {}))
+	close(release)
+
+	require.Eventually(t, func() bool {
+		return p.TryGo(func() {})
+	}, 5*time.Second, time.Millisecond)
+}
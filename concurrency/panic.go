@@ -0,0 +1,178 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// defaultMaxRestarts is how many times PanicRestart restarts a task when Option.MaxRestarts
+// isn't set.
+const defaultMaxRestarts = 3
+
+// PanicPolicy decides what a GoPool does once a panicking task has been reported.
+type PanicPolicy int
+
+const (
+	// PanicLog reports the panic and carries on with the next task. It's the default.
+	PanicLog PanicPolicy = iota
+
+	// PanicCrash reports the panic and panics again with the recovered value,
+	// which crashes the process.
+	PanicCrash
+
+	// PanicRestart reports the panic and runs the task again on the same worker, up to
+	// Option.MaxRestarts times. It gives up early once the task's ctx is done or its
+	// deadline has passed. The Future of a Submit task gets the result of the last run.
+	PanicRestart
+)
+
+func (p PanicPolicy) String() string {
+	switch p {
+	case PanicLog:
+		return "log"
+	case PanicCrash:
+		return "crash"
+	case PanicRestart:
+		return "restart"
+	default:
+		return "unknown"
+	}
+}
+
+// PanicReport describes a task that panicked.
+type PanicReport struct {
+	// Pool is the name of the pool the task ran on.
+	Pool string
+
+	// Label is the task's label set with WithLabel, or its key for KeyedPool tasks.
+	Label string
+
+	// Value is what recover() returned.
+	Value interface{}
+
+	// Stack is the stack of the panicking goroutine.
+	Stack []byte
+
+	// Submitted is when the task was submitted.
+	Submitted time.Time
+
+	// Restarts is how many times PanicRestart had already restarted the task.
+	Restarts int
+
+	// Ctx is the ctx the task was submitted with.
+	Ctx context.Context
+
+	// Values holds the values found in Ctx for Option.PanicContextKeys.
+	Values map[interface{}]interface{}
+}
+
+// String formats r for logs, stack included.
+func (r *PanicReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "panic in pool: %s", r.Pool)
+	if r.Label != "" {
+		fmt.Fprintf(&b, ": task %s", r.Label)
+	}
+	fmt.Fprintf(&b, ": %v", r.Value)
+	if !r.Submitted.IsZero() {
+		fmt.Fprintf(&b, " (submitted at %s", r.Submitted.Format(time.RFC3339Nano))
+		if r.Restarts > 0 {
+			fmt.Fprintf(&b, ", restarted %d times", r.Restarts)
+		}
+		b.WriteString(")")
+	}
+	if len(r.Values) > 0 {
+		fmt.Fprintf(&b, " %v", r.Values)
+	}
+	fmt.Fprintf(&b, ": %s", r.Stack)
+	return b.String()
+}
+
+// WithLabel names the task in its PanicReport, so that reports say which job blew up.
+func WithLabel(label string) TaskOption {
+	return func(t *task) {
+		t.label = label
+	}
+}
+
+// supervise runs t and reports whether it panicked, restarting it as the PanicPolicy allows.
+func (p *GoPool) supervise(t *task) (panicked bool) {
+	for restarts := 0; ; restarts++ {
+		if !p.runOnce(t, restarts) {
+			return panicked
+		}
+		panicked = true
+		if p.panicPolicy != PanicRestart || restarts >= p.maxRestarts() || t.expired() != nil {
+			if t.gaveUp != nil {
+				t.gaveUp()
+			}
+			return true
+		}
+	}
+}
+
+// runOnce runs t, recovering and reporting a panic, and reports whether t panicked.
+func (p *GoPool) runOnce(t *task, restarts int) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			// still deferred: handlers calling debug.Stack() see the panicking goroutine
+			p.handlePanic(t, r, restarts)
+		}
+	}()
+	t.f()
+	return false
+}
+
+// handlePanic reports the panic of t to the panic handlers, or logs it if there are none,
+// then panics again if the policy is PanicCrash.
+func (p *GoPool) handlePanic(t *task, r interface{}, restarts int) {
+	if p.panicHandler != nil {
+		p.panicHandler(t.ctx, r)
+	}
+	if p.panicHandler == nil || p.panicReporter != nil {
+		report := &PanicReport{
+			Pool:      p.name,
+			Label:     t.label,
+			Value:     r,
+			Stack:     debug.Stack(),
+			Submitted: t.submitted,
+			Restarts:  restarts,
+			Ctx:       t.ctx,
+			Values:    p.panicValues(t.ctx),
+		}
+		if p.panicReporter != nil {
+			p.panicReporter(report)
+		} else {
+			log.Printf("GOPOOL: %s", report)
+		}
+	}
+	if p.panicPolicy == PanicCrash {
+		panic(r)
+	}
+}
+
+// panicValues collects the values of Option.PanicContextKeys found in ctx.
+func (p *GoPool) panicValues(ctx context.Context) map[interface{}]interface{} {
+	if ctx == nil || len(p.panicKeys) == 0 {
+		return nil
+	}
+	values := make(map[interface{}]interface{}, len(p.panicKeys))
+	for _, key := range p.panicKeys {
+		if v := ctx.Value(key); v != nil {
+			values[key] = v
+		}
+	}
+	return values
+}
+
+func (p *GoPool) maxRestarts() int {
+	if p.restarts <= 0 {
+		return defaultMaxRestarts
+	}
+	return p.restarts
+}
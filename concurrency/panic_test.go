@@ -0,0 +1,159 @@
+package concurrency
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type panicCtxKey struct{}
+
+func TestGoPool_PanicReport(t *testing.T) {
+	reports := make(chan *PanicReport, 1)
+	o := DefaultOption()
+	o.OnPanic = func(r *PanicReport) { reports <- r }
+	o.PanicContextKeys = []interface{}{panicCtxKey{}, "missing"}
+	p := NewGoPool("TestGoPool_PanicReport", o)
+
+	ctx := context.WithValue(context.Background(), panicCtxKey{}, "req-1")
+	before := time.Now()
+	require.NoError(t, p.CtxGo(ctx, func() { panic("boom") }, WithLabel("job")))
+
+	r := <-reports
+	require.Equal(t, "TestGoPool_PanicReport", r.Pool)
+	require.Equal(t, "job", r.Label)
+	require.Equal(t, "boom", r.Value)
+	require.False(t, r.Submitted.Before(before))
+	require.Zero(t, r.Restarts)
+	require.Equal(t, ctx, r.Ctx)
+	require.Equal(t, map[interface{}]interface{}{panicCtxKey{}: "req-1"}, r.Values)
+	require.Contains(t, string(r.Stack), "TestGoPool_PanicReport")
+	require.True(t, strings.HasPrefix(r.String(), "panic in pool: TestGoPool_PanicReport: task job: boom (submitted at "))
+
+	require.Eventually(t, func() bool { return p.Stats().Panicked == 1 }, time.Second, time.Millisecond)
+}
+
+func TestGoPool_PanicHandlerAndReport(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	o := DefaultOption()
+	o.OnPanic = func(r *PanicReport) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, "report")
+	}
+	p := NewGoPool("TestGoPool_PanicHandlerAndReport", o)
+	p.SetPanicHandler(func(ctx context.Context, r interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, "handler")
+	})
+
+	require.True(t, p.runTask(context.Background(), func() { panic("boom") }))
+	require.Equal(t, []string{"handler", "report"}, calls)
+	require.False(t, p.runTask(context.Background(), func() {}))
+}
+
+func TestGoPool_PanicCrash(t *testing.T) {
+	o := DefaultOption()
+	o.PanicPolicy = PanicCrash
+	o.OnPanic = func(r *PanicReport) {}
+	p := NewGoPool("TestGoPool_PanicCrash", o)
+
+	require.PanicsWithValue(t, "boom", func() {
+		p.runTask(context.Background(), func() { panic("boom") })
+	})
+}
+
+func TestGoPool_PanicRestart(t *testing.T) {
+	var restarts []int
+	o := DefaultOption()
+	o.PanicPolicy = PanicRestart
+	o.MaxRestarts = 2
+	o.OnPanic = func(r *PanicReport) { restarts = append(restarts, r.Restarts) }
+	p := NewGoPool("TestGoPool_PanicRestart", o)
+
+	// recovers after a restart
+	runs := 0
+	require.True(t, p.runTask(context.Background(), func() {
+		if runs++; runs == 1 {
+			panic("boom")
+		}
+	}))
+	require.Equal(t, 2, runs)
+	require.Equal(t, []int{0}, restarts)
+
+	// gives up after MaxRestarts
+	restarts, runs = nil, 0
+	require.True(t, p.runTask(context.Background(), func() {
+		runs++
+		panic("boom")
+	}))
+	require.Equal(t, 3, runs)
+	require.Equal(t, []int{0, 1, 2}, restarts)
+
+	// stops once ctx is done
+	restarts, runs = nil, 0
+	ctx, cancel := context.WithCancel(context.Background())
+	require.True(t, p.runTask(ctx, func() {
+		runs++
+		cancel()
+		panic("boom")
+	}))
+	require.Equal(t, 1, runs)
+}
+
+func TestSubmit_PanicRestart(t *testing.T) {
+	o := DefaultOption()
+	o.PanicPolicy = PanicRestart
+	o.MaxRestarts = 2
+	o.OnPanic = func(r *PanicReport) {}
+	p := NewGoPool("TestSubmit_PanicRestart", o)
+
+	// the future gets the result of the restarted run
+	var runs int32
+	v, err := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			panic("boom")
+		}
+		return 42, ctx.Err()
+	}).Get(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 42, v)
+	require.Equal(t, int32(2), atomic.LoadInt32(&runs))
+
+	// and the *PanicError once the pool gave up
+	atomic.StoreInt32(&runs, 0)
+	_, err = Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&runs, 1)
+		panic("boom")
+	}).Get(context.Background())
+	var perr *PanicError
+	require.ErrorAs(t, err, &perr)
+	require.Equal(t, "boom", perr.Value)
+	require.Equal(t, int32(3), atomic.LoadInt32(&runs))
+}
+
+func TestKeyedPool_PanicLabel(t *testing.T) {
+	reports := make(chan *PanicReport, 1)
+	o := DefaultOption()
+	o.OnPanic = func(r *PanicReport) { reports <- r }
+	k := NewKeyedPool(NewGoPool("TestKeyedPool_PanicLabel", o), 0)
+
+	require.NoError(t, k.GoKey("user-1", func() { panic("boom") }))
+	r := <-reports
+	require.Equal(t, "user-1", r.Label)
+	require.False(t, r.Submitted.IsZero())
+}
+
+func TestPanicPolicy_String(t *testing.T) {
+	require.Equal(t, "log", PanicLog.String())
+	require.Equal(t, "crash", PanicCrash.String())
+	require.Equal(t, "restart", PanicRestart.String())
+	require.Equal(t, "unknown", PanicPolicy(42).String())
+}
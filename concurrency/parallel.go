@@ -0,0 +1,299 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ErrorMode decides how the parallel helpers Map, ForEach, Filter, Reduce and MapChan
+// handle failing items.
+type ErrorMode int
+
+const (
+	// FailFast stops at the first failing item: items not started yet are skipped, and the
+	// context of running ones is cancelled. It's the default.
+	FailFast ErrorMode = iota
+	// CollectAll processes every item and reports all failures joined.
+	CollectAll
+)
+
+// ItemError reports the failure of the item at Index. Panics are reported as a *PanicError.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// Map calls fn for every item on pool, running at most concurrency calls at once, and returns
+// the results in the order of items. concurrency <= 0 means runtime.GOMAXPROCS(0).
+//
+// With FailFast, the default, it returns the first *ItemError and no results. With
+// CollectAll, it returns all results, zero for the failed items, along with their errors.
+func Map[T, R any](ctx context.Context, pool *GoPool, items []T, concurrency int, fn func(ctx context.Context, item T) (R, error), mode ...ErrorMode) ([]R, error) {
+	results := make([]R, len(items))
+	err := forEachIndex(ctx, pool, len(items), concurrency, mode, func(ctx context.Context, i int) error {
+		r, err := fn(ctx, items[i])
+		if err == nil {
+			results[i] = r
+		}
+		return err
+	})
+	if err != nil && !collectAll(mode) {
+		return nil, err
+	}
+	return results, err
+}
+
+// ForEach calls fn for every item on pool, running at most concurrency calls at once.
+// Errors are handled as in Map.
+func ForEach[T any](ctx context.Context, pool *GoPool, items []T, concurrency int, fn func(ctx context.Context, item T) error, mode ...ErrorMode) error {
+	return forEachIndex(ctx, pool, len(items), concurrency, mode, func(ctx context.Context, i int) error {
+		return fn(ctx, items[i])
+	})
+}
+
+// Filter returns the items for which fn reports true, in their original order, calling fn
+// on pool as Map does. With CollectAll, failed items are left out.
+func Filter[T any](ctx context.Context, pool *GoPool, items []T, concurrency int, fn func(ctx context.Context, item T) (bool, error), mode ...ErrorMode) ([]T, error) {
+	keep, err := Map(ctx, pool, items, concurrency, fn, mode...)
+	if keep == nil {
+		return nil, err
+	}
+	var kept []T
+	for i, ok := range keep {
+		if ok {
+			kept = append(kept, items[i])
+		}
+	}
+	return kept, err
+}
+
+// Reduce maps items with fn on pool as Map does, then folds the results into acc in the
+// order of items. With CollectAll, the results of failed items are left out, and nothing
+// is folded if some items weren't processed at all.
+func Reduce[T, R, A any](ctx context.Context, pool *GoPool, items []T, concurrency int, fn func(ctx context.Context, item T) (R, error), acc A, fold func(acc A, result R) A, mode ...ErrorMode) (A, error) {
+	results, err := Map(ctx, pool, items, concurrency, fn, mode...)
+	if results == nil {
+		return acc, err
+	}
+
+	var failed map[int]bool
+	if err != nil {
+		failed = make(map[int]bool)
+		for _, e := range unjoin(err) {
+			var ie *ItemError
+			if !errors.As(e, &ie) {
+				// not every item was processed, e.g. ctx is done
+				return acc, err
+			}
+			failed[ie.Index] = true
+		}
+	}
+	for i, r := range results {
+		if !failed[i] {
+			acc = fold(acc, r)
+		}
+	}
+	return acc, err
+}
+
+// MapChan calls fn for every item received from in on pool, running at most concurrency
+// calls at once, and sends the results to the returned channel in the order the items were
+// received. concurrency <= 0 means runtime.GOMAXPROCS(0).
+//
+// The result channel is closed once in is closed and drained, ctx is done, or, with
+// FailFast, an item failed. The error channel then yields the error, if any, and is closed.
+// With CollectAll, failed items are left out of the results and reported together.
+// Callers must drain the results or cancel ctx.
+func MapChan[T, R any](ctx context.Context, pool *GoPool, in <-chan T, concurrency int, fn func(ctx context.Context, item T) (R, error), mode ...ErrorMode) (<-chan R, <-chan error) {
+	if pool == nil {
+		pool = defaultGoPool
+	}
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	collect := collectAll(mode)
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+
+	type slot struct {
+		index int
+		done  chan struct{}
+		v     R
+		err   error
+	}
+	var (
+		out   = make(chan R)
+		errc  = make(chan error, 1)
+		sem   = make(chan struct{}, concurrency)
+		slots = make(chan *slot, concurrency)
+	)
+
+	// feed items to the pool, in order, while fewer than concurrency are in flight
+	go func() {
+		defer close(slots)
+		for i := 0; ; i++ {
+			var item T
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				item = v
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			s := &slot{index: i, done: make(chan struct{})}
+			slots <- s // never blocks: sem bounds the slots in flight
+
+			t := newTask(ctx, nil, nil)
+			t.dropped = func(err error) {
+				s.err = err
+				close(s.done)
+			}
+			t.f = func() {
+				s.err = protect(ctx, func(ctx context.Context) (err error) {
+					s.v, err = fn(ctx, item)
+					return err
+				})
+				close(s.done)
+			}
+			if err := pool.submit(t); err != nil {
+				t.dropped(err)
+			}
+		}
+	}()
+
+	// emit results in order
+	go func() {
+		var errs []error
+		defer func() {
+			cancel()
+			close(out)
+			if err := parent.Err(); err != nil {
+				errs = append(errs, err)
+			}
+			if err := joinErrors(errs); err != nil {
+				errc <- err
+			}
+			close(errc)
+		}()
+
+		for s := range slots {
+			select {
+			case <-s.done:
+			case <-ctx.Done():
+				return
+			}
+			<-sem
+
+			if s.err != nil {
+				if ctx.Err() != nil {
+					return // dropped because ctx is done
+				}
+				errs = append(errs, &ItemError{Index: s.index, Err: s.err})
+				if !collect {
+					return
+				}
+				continue
+			}
+			select {
+			case out <- s.v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, errc
+}
+
+// forEachIndex calls fn for the indexes 0 to n-1 in a Group on pool.
+func forEachIndex(ctx context.Context, pool *GoPool, n, concurrency int, mode []ErrorMode, fn func(ctx context.Context, i int) error) error {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	collect := collectAll(mode)
+	g, gctx := NewGroup(ctx, pool)
+	g.SetLimit(concurrency)
+
+	var errs []error
+	if collect {
+		errs = make([]error, n)
+	}
+	i := 0
+	for ; i < n && gctx.Err() == nil; i++ {
+		i := i
+		g.Go(func(ctx context.Context) error {
+			err := protect(ctx, func(ctx context.Context) error {
+				return fn(ctx, i)
+			})
+			if err == nil {
+				return nil
+			}
+			err = &ItemError{Index: i, Err: err}
+			if collect {
+				errs[i] = err // each index is written by one task only
+				return nil
+			}
+			return err
+		})
+	}
+	err := g.Wait()
+	if err == nil && i < n {
+		err = ctx.Err()
+	}
+	if !collect {
+		return err
+	}
+
+	var all []error
+	for _, e := range errs {
+		if e != nil {
+			all = append(all, e)
+		}
+	}
+	if err != nil {
+		all = append(all, err)
+	}
+	return joinErrors(all)
+}
+
+func collectAll(mode []ErrorMode) bool {
+	return len(mode) > 0 && mode[0] == CollectAll
+}
+
+// joinErrors returns nil, the only error, or all of them joined.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return errors.Join(errs...)
+	}
+}
+
+// unjoin returns the errors joined in err, or err alone.
+func unjoin(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
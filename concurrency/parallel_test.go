@@ -0,0 +1,218 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func square(ctx context.Context, i int) (int, error) {
+	return i * i, nil
+}
+
+func failOdd(ctx context.Context, i int) (int, error) {
+	if i%2 == 1 {
+		return 0, fmt.Errorf("odd %d", i)
+	}
+	return i, nil
+}
+
+func TestMap(t *testing.T) {
+	p := NewGoPool("TestMap", nil)
+
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	var running, peak int32
+	results, err := Map(context.Background(), p, items, 4, func(ctx context.Context, i int) (int, error) {
+		cur := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if cur <= old || atomic.CompareAndSwapInt32(&peak, old, cur) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Microsecond)
+		return square(ctx, i)
+	})
+	require.NoError(t, err)
+	for i, r := range results {
+		require.Equal(t, i*i, r)
+	}
+	require.LessOrEqual(t, atomic.LoadInt32(&peak), int32(4))
+
+	results, err = Map(context.Background(), p, []int(nil), 0, square)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestMap_FailFast(t *testing.T) {
+	p := NewGoPool("TestMap_FailFast", nil)
+
+	var calls int32
+	results, err := Map(context.Background(), p, []int{0, 1, 2, 3, 4, 5, 6, 7}, 1, func(ctx context.Context, i int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return failOdd(ctx, i)
+	})
+	require.Nil(t, results)
+	var ie *ItemError
+	require.True(t, errors.As(err, &ie))
+	require.Equal(t, 1, ie.Index)
+	require.EqualError(t, err, "item 1: odd 1")
+	require.Less(t, atomic.LoadInt32(&calls), int32(8))
+}
+
+func TestMap_CollectAll(t *testing.T) {
+	p := NewGoPool("TestMap_CollectAll", nil)
+
+	results, err := Map(context.Background(), p, []int{0, 1, 2, 3}, 2, failOdd, CollectAll)
+	require.Equal(t, []int{0, 0, 2, 0}, results)
+	require.EqualError(t, err, "item 1: odd 1\nitem 3: odd 3")
+
+	_, err = Map(context.Background(), p, []int{1}, 2, func(ctx context.Context, i int) (int, error) {
+		panic("boom")
+	}, CollectAll)
+	var pe *PanicError
+	require.True(t, errors.As(err, &pe))
+}
+
+func TestMap_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Map(ctx, NewGoPool("TestMap_Cancelled", nil), []int{1, 2, 3}, 1, square)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestForEach(t *testing.T) {
+	p := NewGoPool("TestForEach", nil)
+
+	var sum int64
+	err := ForEach(context.Background(), p, []int{1, 2, 3, 4}, 0, func(ctx context.Context, i int) error {
+		atomic.AddInt64(&sum, int64(i))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), sum)
+
+	err = ForEach(context.Background(), p, []int{1, 2, 3}, 0, func(ctx context.Context, i int) error {
+		_, err := failOdd(ctx, i)
+		return err
+	}, CollectAll)
+	require.EqualError(t, err, "item 0: odd 1\nitem 2: odd 3")
+}
+
+func TestFilter(t *testing.T) {
+	p := NewGoPool("TestFilter", nil)
+	even := func(ctx context.Context, i int) (bool, error) {
+		return i%2 == 0, nil
+	}
+
+	kept, err := Filter(context.Background(), p, []int{5, 4, 3, 2, 1, 0}, 3, even)
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 2, 0}, kept)
+
+	kept, err = Filter(context.Background(), p, []int{1, 2, 3, 4}, 3, func(ctx context.Context, i int) (bool, error) {
+		if i == 3 {
+			return true, errors.New("x")
+		}
+		return true, nil
+	}, CollectAll)
+	require.Error(t, err)
+	require.Equal(t, []int{1, 2, 4}, kept)
+}
+
+func TestReduce(t *testing.T) {
+	p := NewGoPool("TestReduce", nil)
+	concat := func(acc string, r int) string {
+		return acc + fmt.Sprint(r)
+	}
+
+	s, err := Reduce(context.Background(), p, []int{1, 2, 3, 4}, 2, square, "", concat)
+	require.NoError(t, err)
+	require.Equal(t, "14916", s)
+
+	s, err = Reduce(context.Background(), p, []int{0, 1, 2, 3, 4}, 2, failOdd, ">", concat, CollectAll)
+	require.Error(t, err)
+	require.Equal(t, ">024", s)
+
+	s, err = Reduce(context.Background(), p, []int{0, 1, 2}, 2, failOdd, ">", concat)
+	require.Error(t, err)
+	require.Equal(t, ">", s)
+}
+
+func feed(items ...int) <-chan int {
+	in := make(chan int, len(items))
+	for _, i := range items {
+		in <- i
+	}
+	close(in)
+	return in
+}
+
+func TestMapChan(t *testing.T) {
+	p := NewGoPool("TestMapChan", nil)
+
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 0; i < 100; i++ {
+			in <- i
+		}
+	}()
+	out, errc := MapChan(context.Background(), p, in, 8, func(ctx context.Context, i int) (int, error) {
+		time.Sleep(time.Duration(100-i) * time.Microsecond) // later items finish first
+		return square(ctx, i)
+	})
+
+	i := 0
+	for r := range out {
+		require.Equal(t, i*i, r)
+		i++
+	}
+	require.Equal(t, 100, i)
+	require.NoError(t, <-errc)
+}
+
+func TestMapChan_Errors(t *testing.T) {
+	p := NewGoPool("TestMapChan_Errors", nil)
+
+	out, errc := MapChan(context.Background(), p, feed(0, 2, 1, 4, 6), 1, failOdd)
+	var got []int
+	for r := range out {
+		got = append(got, r)
+	}
+	require.Equal(t, []int{0, 2}, got)
+	require.EqualError(t, <-errc, "item 2: odd 1")
+
+	out, errc = MapChan(context.Background(), p, feed(0, 1, 2, 3, 4), 2, failOdd, CollectAll)
+	got = nil
+	for r := range out {
+		got = append(got, r)
+	}
+	require.Equal(t, []int{0, 2, 4}, got)
+	require.EqualError(t, <-errc, "item 1: odd 1\nitem 3: odd 3")
+}
+
+func TestMapChan_Cancel(t *testing.T) {
+	p := NewGoPool("TestMapChan_Cancel", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int) // never closed
+	out, errc := MapChan(ctx, p, in, 2, square)
+	in <- 3
+	require.Equal(t, 9, <-out)
+	cancel()
+
+	for range out {
+	}
+	require.ErrorIs(t, <-errc, context.Canceled)
+	_, ok := <-errc
+	require.False(t, ok)
+}
@@ -18,8 +18,7 @@ package concurrency
 
 import (
 	"context"
-	"log"
-	"runtime/debug"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -33,11 +32,57 @@ type Option struct {
 	// WorkerMaxAge is the max age of a worker in pool.
 	WorkerMaxAge time.Duration
 
-	// TaskChanBuffer is the size of task queue length.
+	// TaskChanBuffer is the size of task queue length, per priority level.
 	// if it's full, we will fall back to use `go` directly without using pool.
 	// normally, the queue length should be small,
 	// coz we will create new workers to pick tasks if necessary.
 	TaskChanBuffer int
+
+	// MaxWorkers is the hard cap on workers running tasks concurrently.
+	// 0 means no cap. It's ignored if Adaptive is set.
+	MaxWorkers int
+
+	// Overflow decides what happens when the task queue is full.
+	// With MaxWorkers set, OverflowSpawn would break the cap and is treated as OverflowBlock.
+	Overflow OverflowPolicy
+
+	// OnDropped, if set, is called for every accepted task that is discarded without running,
+	// with the task's ctx and the reason: ctx.Err() or context.DeadlineExceeded for expired tasks,
+	// ErrPoolFull for tasks evicted by OverflowDropOldest, and ErrPoolClosed for tasks dropped
+	// by Shutdown or ShutdownNow.
+	OnDropped func(ctx context.Context, reason error)
+
+	// OnPanic, if set, receives a PanicReport for every panicking task. Without it, and
+	// without a handler set by SetPanicHandler, reports are logged with log.Printf.
+	OnPanic func(r *PanicReport)
+
+	// PanicPolicy decides what happens once a panic has been reported. Defaults to PanicLog.
+	PanicPolicy PanicPolicy
+
+	// MaxRestarts is how many times PanicRestart restarts a task. 0 means 3.
+	MaxRestarts int
+
+	// PanicContextKeys lists the ctx keys whose values are copied into PanicReport.Values.
+	PanicContextKeys []interface{}
+
+	// Exporter, if set, receives per-task latencies as they are observed.
+	Exporter StatsExporter
+
+	// ContextHooks carry goroutine-local state from submitters into tasks, see ContextHook.
+	ContextHooks []ContextHook
+
+	// RateLimiter, if set, caps how fast tasks start. Workers wait for a token before
+	// running a task, and drop it if its ctx is done meanwhile.
+	RateLimiter *RateLimiter
+
+	// Adaptive, if set, lets a controller move the worker cap between Adaptive.MinWorkers
+	// and Adaptive.MaxWorkers as load changes, instead of the fixed MaxWorkers.
+	Adaptive *AdaptiveOption
+
+	// PriorityWeights sets how workers share their time between priority levels when all
+	// have tasks queued: a level with weight w gets w of every sum-of-weights tasks picked,
+	// so low priority work is slowed down but never starved. Nil means DefaultPriorityWeights.
+	PriorityWeights map[Priority]int
 }
 
 // DefaultOption returns the default values of Option.
@@ -52,13 +97,13 @@ func DefaultOption() *Option {
 var defaultGoPool = NewGoPool("__default__", nil)
 
 // Go runs the given func in background
-func Go(f func()) {
-	defaultGoPool.Go(f)
+func Go(f func()) error {
+	return defaultGoPool.Go(f)
 }
 
 // CtxGo runs the given func in background, and it passes ctx to panic handler when happens.
-func CtxGo(ctx context.Context, f func()) {
-	defaultGoPool.CtxGo(ctx, f)
+func CtxGo(ctx context.Context, f func(), opts ...TaskOption) error {
+	return defaultGoPool.CtxGo(ctx, f, opts...)
 }
 
 // SetPanicHandler sets a func for handling panic cases.
@@ -69,24 +114,66 @@ func SetPanicHandler(f func(ctx context.Context, r interface{})) {
 }
 
 type task struct {
-	ctx context.Context
-	f   func()
+	ctx       context.Context
+	f         func()
+	submitted time.Time
+	priority  Priority
+	label     string
+	states    []interface{} // captured by the pool's ContextHooks
+
+	// internal marks a task that only runs other tasks, like a KeyedPool runner.
+	// It skips the pool's RateLimiter and isn't reported when dropped; the
+	// tasks it carries are.
+	internal bool
+
+	// deadline, if set, is the latest time the task may start.
+	deadline time.Time
+
+	// dropped, if set, is called when the task is removed from the queue without running.
+	dropped func(err error)
+
+	// gaveUp, if set, is called once the task panicked and won't be restarted.
+	gaveUp func()
 }
 
 // GoPool represents a simple worker pool which manages goroutines for background tasks.
 type GoPool struct {
 	name string
 
-	workers int32
-	maxIdle int32
-	maxage  int64 // milliseconds
-
-	panicHandler func(ctx context.Context, r interface{})
-
-	tasks     chan task
+	workers    int32
+	maxIdle    int32
+	maxWorkers int32 // changed by the adaptive controller, if any
+	maxage     int64 // milliseconds
+	overflow   OverflowPolicy
+
+	panicHandler   func(ctx context.Context, r interface{})
+	panicReporter  func(r *PanicReport)
+	panicPolicy    PanicPolicy
+	panicKeys      []interface{}
+	restarts       int // Option.MaxRestarts
+	droppedHandler func(ctx context.Context, reason error)
+	exporter       StatsExporter
+	hooks          []ContextHook
+	limiter        *RateLimiter
+	stats          poolStats
+
+	queues    [numPriorities]chan task // by level, see Priority
+	schedule  []int                    // levels in weighted order, see next
+	turn      uint32
 	unixMilli int64
 
-	createWorker func()
+	// mu guards closed: submitters hold it for reading while queueing,
+	// so that once Shutdown sets closed under the write lock no task can sneak in.
+	mu           sync.RWMutex
+	closed       int32
+	aborted      int32 // set by ShutdownNow: workers exit without draining the queue
+	closing      chan struct{}
+	closingOnce  sync.Once
+	pending      int64 // accepted tasks that haven't finished or been dropped
+	drained      chan struct{}
+	drainedOnce  sync.Once
+	sched        scheduler
+	createWorker func(id int32)
 }
 
 // NewGoPool create a new instance for goroutine worker
@@ -95,39 +182,163 @@ func NewGoPool(name string, o *Option) *GoPool {
 		o = DefaultOption()
 	}
 	p := &GoPool{
-		name:    name,
-		tasks:   make(chan task, o.TaskChanBuffer),
-		maxage:  o.WorkerMaxAge.Milliseconds(),
-		maxIdle: int32(o.MaxIdleWorkers),
+		name:       name,
+		schedule:   prioritySchedule(o.PriorityWeights),
+		maxage:     o.WorkerMaxAge.Milliseconds(),
+		maxIdle:    int32(o.MaxIdleWorkers),
+		maxWorkers: int32(o.MaxWorkers),
+		overflow:   o.Overflow,
+		closing:    make(chan struct{}),
+		drained:    make(chan struct{}),
+
+		panicReporter:  o.OnPanic,
+		panicPolicy:    o.PanicPolicy,
+		panicKeys:      o.PanicContextKeys,
+		restarts:       o.MaxRestarts,
+		droppedHandler: o.OnDropped,
+		exporter:       o.Exporter,
+		hooks:          o.ContextHooks,
+		limiter:        o.RateLimiter,
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan task, o.TaskChanBuffer)
+	}
+	if o.Adaptive != nil {
+		p.maxWorkers = int32(o.Adaptive.minWorkers())
+	}
+	if p.maxWorkers > 0 && p.overflow == OverflowSpawn {
+		p.overflow = OverflowBlock
+	}
+	if o.Adaptive != nil {
+		go p.runAdaptive(*o.Adaptive)
 	}
 
 	// fix: func literal escapes to heap
-	p.createWorker = func() {
-		p.runWorker()
+	p.createWorker = func(id int32) {
+		p.runWorker(id)
 	}
 	return p
 }
 
 // Go runs the given func in background
-func (p *GoPool) Go(f func()) {
-	p.CtxGo(context.Background(), f)
+func (p *GoPool) Go(f func()) error {
+	return p.CtxGo(context.Background(), f)
 }
 
 // CtxGo runs the given func in background, and it passes ctx to panic handler when happens.
-func (p *GoPool) CtxGo(ctx context.Context, f func()) {
+//
+// If the task queue is full, the pool's OverflowPolicy applies. It returns ErrPoolFull
+// with OverflowReject, or ctx.Err() if ctx is done while blocked with OverflowBlock.
+// After Shutdown or ShutdownNow it returns ErrPoolClosed.
+//
+// Tasks run at PriorityNormal unless WithPriority says otherwise, see CtxGoWithPriority.
+// A queued task whose ctx is done, or whose deadline set by WithDeadline or WithTimeout
+// has passed, by the time a worker picks it up is dropped instead of run.
+// WithLabel names the task in the PanicReport should it panic.
+func (p *GoPool) CtxGo(ctx context.Context, f func(), opts ...TaskOption) error {
+	return p.submit(newTask(ctx, f, opts))
+}
+
+// submit queues t or runs it as the OverflowPolicy dictates.
+func (p *GoPool) submit(t task) error {
+	if t.states == nil {
+		t.states = p.capture(t.ctx)
+	}
+	queued, err := p.enqueue(t, true)
+	if err != nil {
+		return err
+	}
+	if queued {
+		p.wakeWorker()
+		return nil
+	}
+	p.runOverflow(t)
+	return nil
+}
+
+// TryGo queues f without blocking and reports whether it was accepted.
+// Unlike Go, it never applies the OverflowPolicy.
+func (p *GoPool) TryGo(f func()) bool {
+	return p.TryCtxGo(context.Background(), f)
+}
+
+// TryCtxGo is TryGo with a ctx passed to the panic handler and the TaskOptions of CtxGo.
+func (p *GoPool) TryCtxGo(ctx context.Context, f func(), opts ...TaskOption) bool {
+	return p.trySubmit(newTask(ctx, f, opts))
+}
+
+// trySubmit queues t without blocking and without applying the OverflowPolicy.
+func (p *GoPool) trySubmit(t task) bool {
+	if t.states == nil {
+		t.states = p.capture(t.ctx)
+	}
+	queued, _ := p.enqueue(t, false)
+	if queued {
+		p.wakeWorker()
+	}
+	return queued
+}
+
+// enqueue puts t in the task queue, applying the OverflowPolicy if overflow is set
+// and the queue is full. It reports false with a nil error when the policy wants t
+// to run outside the queue, in which case t is already counted as pending.
+func (p *GoPool) enqueue(t task, overflow bool) (queued bool, err error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if atomic.LoadInt32(&p.closed) != 0 {
+		atomic.AddUint64(&p.stats.rejected, 1)
+		return false, ErrPoolClosed
+	}
+
+	atomic.AddInt64(&p.pending, 1)
 	select {
-	case p.tasks <- task{ctx: ctx, f: f}:
+	case p.queues[t.priority.level()] <- t:
+		p.stats.submit(t)
+		return true, nil
 	default:
-		// full? fall back to use go directly
-		go p.runTask(ctx, f)
-		return
 	}
+	if overflow {
+		queued, err = p.handleOverflow(t)
+	} else {
+		err = ErrPoolFull
+	}
+	if err != nil {
+		atomic.AddUint64(&p.stats.rejected, 1)
+		p.taskDone()
+		return false, err
+	}
+	p.stats.submit(t)
+	return queued, nil
+}
+
+// wakeWorker creates a new worker if queued tasks are waiting.
+func (p *GoPool) wakeWorker() {
 	// luckily ... it's true when there're many workers.
-	if len(p.tasks) == 0 {
+	if p.queued() == 0 {
 		return
 	}
 	// all worker is busy, create a new one
-	go p.createWorker()
+	p.spawnWorker()
+}
+
+// spawnWorker starts a worker unless MaxWorkers is reached.
+func (p *GoPool) spawnWorker() {
+	var id int32
+	if max := atomic.LoadInt32(&p.maxWorkers); max <= 0 {
+		id = atomic.AddInt32(&p.workers, 1)
+	} else {
+		for {
+			n := atomic.LoadInt32(&p.workers)
+			if n >= max {
+				return
+			}
+			if atomic.CompareAndSwapInt32(&p.workers, n, n+1) {
+				id = n + 1
+				break
+			}
+		}
+	}
+	go p.createWorker(id)
 }
 
 // SetPanicHandler sets a func for handling panic cases.
@@ -135,49 +346,57 @@ func (p *GoPool) CtxGo(ctx context.Context, f func()) {
 // Panic handler takes two args, `ctx` and `r`.
 // `ctx` is the one provided when calling CtxGo, and `r` is returned by recover()
 //
-// By default, GoPool will use log.Printf to record the err and stack.
+// By default, GoPool will use log.Printf to record the err and stack,
+// see Option.OnPanic for structured reports.
 //
 // It's recommended to set your own handler.
 func (p *GoPool) SetPanicHandler(f func(ctx context.Context, r interface{})) {
 	p.panicHandler = f
 }
 
-func (p *GoPool) runTask(ctx context.Context, f func()) {
-	defer func(p *GoPool, ctx context.Context) {
-		if r := recover(); r != nil {
-			if p.panicHandler != nil {
-				p.panicHandler(ctx, r)
-			} else {
-				log.Printf("GOPOOL: panic in pool: %s: %v: %s", p.name, r, debug.Stack())
-			}
-		}
-	}(p, ctx)
-	f()
+// runTask runs f, recovering and reporting a panic, and reports whether f panicked.
+func (p *GoPool) runTask(ctx context.Context, f func()) (panicked bool) {
+	return p.supervise(&task{ctx: ctx, f: f})
 }
 
 func (p *GoPool) CurrentWorkers() int {
 	return int(atomic.LoadInt32(&p.workers))
 }
 
-func (p *GoPool) runWorker() {
-	id := atomic.AddInt32(&p.workers, 1)
-	defer atomic.AddInt32(&p.workers, -1)
+// runWorker runs queued tasks. The caller must have counted it in p.workers.
+func (p *GoPool) runWorker(id int32) {
+	retired := false
+	defer func() {
+		if retired {
+			return
+		}
+		atomic.AddInt32(&p.workers, -1)
+		// a capped pool may have skipped spawning while we were exiting
+		if atomic.LoadInt32(&p.maxWorkers) > 0 && p.queued() > 0 {
+			p.spawnWorker()
+		}
+	}()
 
 	if id > p.maxIdle {
 		// drain task chan and exit without waiting
-		for {
-			select {
-			case t := <-p.tasks:
-				p.runTask(t.ctx, t.f)
-			default:
-				return
-			}
-		}
+		p.drainQueue()
+		return
 	}
 
 	createdAt := time.Now().UnixMilli() // for checking maxage
-	for t := range p.tasks {
-		p.runTask(t.ctx, t.f)
+	for {
+		t, ok := p.take()
+		if !ok {
+			// shutting down: finish what's queued, then exit
+			if atomic.LoadInt32(&p.aborted) == 0 {
+				p.drainQueue()
+			}
+			return
+		}
+		p.runQueued(t)
+		if retired = p.retire(); retired {
+			return
+		}
 
 		now := atomic.LoadInt64(&p.unixMilli)
 
@@ -198,8 +417,105 @@ func (p *GoPool) runWorker() {
 	}
 }
 
+// drainQueue runs queued tasks until the queue is empty.
+func (p *GoPool) drainQueue() {
+	for {
+		t, ok := p.next()
+		if !ok {
+			return
+		}
+		p.runQueued(t)
+	}
+}
+
+// runQueued runs a task taken from the queue.
+func (p *GoPool) runQueued(t task) {
+	if t.f == nil { // noopTask
+		return
+	}
+	atomic.AddInt32(&p.stats.busy, 1)
+	p.run(t)
+	atomic.AddInt32(&p.stats.busy, -1)
+}
+
+// run runs an accepted task, or drops it if it expired while waiting.
+func (p *GoPool) run(t task) {
+	if err := t.expired(); err != nil {
+		p.dropTask(t, err)
+		return
+	}
+	if p.limiter != nil && !t.internal {
+		// the deadline may pass while waiting for the limiter
+		err := p.throttle(t.ctx)
+		if err == nil {
+			err = t.expired()
+		}
+		if err != nil {
+			p.dropTask(t, err)
+			return
+		}
+	}
+
+	start := time.Now()
+	wait := start.Sub(t.submitted)
+	p.stats.queueWait.observe(wait)
+	p.stats.levels[t.priority.level()].queueWait.observe(wait)
+	if p.exporter != nil {
+		p.exporter.ObserveQueueWait(p.name, wait)
+	}
+
+	atomic.AddInt32(&p.stats.running, 1)
+	cleanup := p.restore(t.ctx, t.states)
+	panicked := p.supervise(&t)
+	cleanup()
+	atomic.AddInt32(&p.stats.running, -1)
+
+	exec := time.Since(start)
+	p.stats.execTime.observe(exec)
+	if panicked {
+		atomic.AddUint64(&p.stats.panicked, 1)
+	}
+	atomic.AddUint64(&p.stats.completed, 1)
+	atomic.AddUint64(&p.stats.levels[t.priority.level()].completed, 1)
+	if p.exporter != nil {
+		p.exporter.ObserveExec(p.name, exec, panicked)
+	}
+	p.taskDone()
+}
+
+// dropTask discards an accepted task that won't run.
+func (p *GoPool) dropTask(t task, err error) {
+	if t.f == nil { // noopTask
+		return
+	}
+	if t.dropped != nil {
+		t.dropped(err)
+	}
+	if !t.internal {
+		atomic.AddUint64(&p.stats.levels[t.priority.level()].dropped, 1)
+		p.reportDropped(t.ctx, err)
+	}
+	p.taskDone()
+}
+
+// reportDropped counts a dropped task and passes it on to Option.OnDropped.
+func (p *GoPool) reportDropped(ctx context.Context, err error) {
+	atomic.AddUint64(&p.stats.dropped, 1)
+	if p.droppedHandler != nil {
+		p.droppedHandler(ctx, err)
+	}
+}
+
+// taskDone marks an accepted task as finished or dropped.
+func (p *GoPool) taskDone() {
+	if atomic.AddInt64(&p.pending, -1) == 0 && atomic.LoadInt32(&p.closed) != 0 {
+		p.drainedOnce.Do(func() { close(p.drained) })
+	}
+}
+
 // noopTask is used by runTicker() to wake up workers and checks their age.
-var noopTask = task{f: func() {}}
+// It has no func, so it's not counted as pending.
+var noopTask = task{}
 
 func (p *GoPool) runTicker() {
 	// mark it zero to trigger ticker to be created when we have active workers
@@ -217,11 +533,20 @@ func (p *GoPool) runTicker() {
 	t := time.NewTicker(d)
 	defer t.Stop()
 
-	for now := range t.C {
-		if p.CurrentWorkers() == 0 {
+	for {
+		select {
+		case now := <-t.C:
+			if p.CurrentWorkers() == 0 {
+				return
+			}
+			atomic.StoreInt64(&p.unixMilli, now.UnixMilli())
+			select {
+			case p.queues[PriorityNormal.level()] <- noopTask:
+			case <-p.closing:
+				return
+			}
+		case <-p.closing:
 			return
 		}
-		atomic.StoreInt64(&p.unixMilli, now.UnixMilli())
-		p.tasks <- noopTask
 	}
 }
@@ -97,8 +97,8 @@ func TestGoPool_Full(t *testing.T) {
 	for i := 0; i < n; i++ {
 		p.Go(func() { atomic.AddInt32(&v, 1) })
 	}
-	time.Sleep(10 * time.Millisecond) // wait all goroutines done
-	require.Equal(t, int32(n), atomic.LoadInt32(&v))
+	// wait all goroutines done
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&v) == int32(n) }, time.Second, time.Millisecond)
 }
 
 func TestGoPool_MaxIdle(t *testing.T) {
@@ -111,9 +111,10 @@ func TestGoPool_MaxIdle(t *testing.T) {
 	for i := 0; i < n; i++ {
 		p.Go(func() { atomic.AddInt32(&v, 1) })
 	}
-	time.Sleep(10 * time.Millisecond) // wait all goroutines done
-	require.Equal(t, int32(n), atomic.LoadInt32(&v))
-	require.Equal(t, o.MaxIdleWorkers, p.CurrentWorkers())
+	// wait all goroutines done
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&v) == int32(n) }, time.Second, time.Millisecond)
+	// the last tasks ran, but the workers beyond MaxIdleWorkers may not have exited yet
+	require.Eventually(t, func() bool { return p.CurrentWorkers() == o.MaxIdleWorkers }, time.Second, time.Millisecond)
 }
 
 // ======== Benchmarks ...
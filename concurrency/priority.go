@@ -0,0 +1,165 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Priority is the scheduling priority of a task. The zero value is PriorityNormal.
+type Priority int
+
+const (
+	// PriorityLow is for background jobs that may wait.
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the priority of tasks submitted without one.
+	PriorityNormal
+	// PriorityHigh is for latency-critical work such as health checks and user-facing requests.
+	PriorityHigh
+)
+
+// numPriorities is the number of priority levels, each with its own queue.
+const numPriorities = 3
+
+func (pr Priority) String() string {
+	switch pr {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("Priority(%d)", int(pr))
+	}
+}
+
+// level returns the index of pr's queue, 0 being the most important.
+// Priorities out of range are clamped.
+func (pr Priority) level() int {
+	switch {
+	case pr > PriorityHigh:
+		pr = PriorityHigh
+	case pr < PriorityLow:
+		pr = PriorityLow
+	}
+	return int(PriorityHigh - pr)
+}
+
+// DefaultPriorityWeights are the weights used when Option.PriorityWeights is nil.
+var DefaultPriorityWeights = map[Priority]int{
+	PriorityHigh:   4,
+	PriorityNormal: 2,
+	PriorityLow:    1,
+}
+
+// WithPriority runs the task at the given priority.
+func WithPriority(pr Priority) TaskOption {
+	return func(t *task) {
+		t.priority = pr
+	}
+}
+
+// GoWithPriority is Go at the given priority.
+func (p *GoPool) GoWithPriority(pr Priority, f func()) error {
+	return p.CtxGoWithPriority(context.Background(), pr, f)
+}
+
+// CtxGoWithPriority is CtxGo at the given priority.
+func (p *GoPool) CtxGoWithPriority(ctx context.Context, pr Priority, f func(), opts ...TaskOption) error {
+	t := newTask(ctx, f, opts)
+	t.priority = pr
+	return p.submit(t)
+}
+
+// GoWithPriority runs the given func in background at the given priority.
+func GoWithPriority(pr Priority, f func()) error {
+	return defaultGoPool.GoWithPriority(pr, f)
+}
+
+// CtxGoWithPriority is CtxGo at the given priority.
+func CtxGoWithPriority(ctx context.Context, pr Priority, f func(), opts ...TaskOption) error {
+	return defaultGoPool.CtxGoWithPriority(ctx, pr, f, opts...)
+}
+
+// prioritySchedule interleaves the levels by weight with smooth weighted round-robin,
+// e.g. weights 4, 2, 1 give high, normal, high, low, high, normal, high.
+func prioritySchedule(weights map[Priority]int) []int {
+	if weights == nil {
+		weights = DefaultPriorityWeights
+	}
+	var w [numPriorities]int
+	total := 0
+	for pr := PriorityHigh; pr >= PriorityLow; pr-- {
+		w[pr.level()] = weights[pr]
+		if w[pr.level()] < 1 {
+			w[pr.level()] = 1
+		}
+		total += w[pr.level()]
+	}
+
+	schedule := make([]int, 0, total)
+	var current [numPriorities]int
+	for len(schedule) < total {
+		best := 0
+		for i := range current {
+			current[i] += w[i]
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		schedule = append(schedule, best)
+	}
+	return schedule
+}
+
+// next takes a queued task without blocking. It tries the level whose turn it is in the
+// weighted schedule first, so that under load every level gets its share, then the
+// others from the most important down.
+func (p *GoPool) next() (task, bool) {
+	turn := atomic.AddUint32(&p.turn, 1)
+	first := p.schedule[turn%uint32(len(p.schedule))]
+	select {
+	case t := <-p.queues[first]:
+		return t, true
+	default:
+	}
+	for i := range p.queues {
+		if i == first {
+			continue
+		}
+		select {
+		case t := <-p.queues[i]:
+			return t, true
+		default:
+		}
+	}
+	return task{}, false
+}
+
+// take waits for a queued task. It reports false once the pool is closing.
+func (p *GoPool) take() (task, bool) {
+	if t, ok := p.next(); ok {
+		return t, true
+	}
+	select {
+	case t := <-p.queues[0]:
+		return t, true
+	case t := <-p.queues[1]:
+		return t, true
+	case t := <-p.queues[2]:
+		return t, true
+	case <-p.closing:
+		return task{}, false
+	}
+}
+
+// queued returns the number of tasks waiting in all queues.
+func (p *GoPool) queued() int {
+	n := 0
+	for _, q := range p.queues {
+		n += len(q)
+	}
+	return n
+}
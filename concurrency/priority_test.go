@@ -0,0 +1,81 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrioritySchedule(t *testing.T) {
+	require.Equal(t, []int{0, 1, 0, 2, 0, 1, 0}, prioritySchedule(nil))
+	require.Equal(t, []int{0, 1, 2}, prioritySchedule(map[Priority]int{}))
+	require.Equal(t, []int{2, 0, 2, 1, 2}, prioritySchedule(map[Priority]int{PriorityLow: 3}))
+}
+
+func TestPriority_Level(t *testing.T) {
+	require.Equal(t, 0, PriorityHigh.level())
+	require.Equal(t, 1, PriorityNormal.level())
+	require.Equal(t, 2, PriorityLow.level())
+	require.Equal(t, 0, Priority(10).level())
+	require.Equal(t, 2, Priority(-10).level())
+	require.Equal(t, "high", PriorityHigh.String())
+	require.Equal(t, "Priority(7)", Priority(7).String())
+}
+
+func TestGoPool_Priority(t *testing.T) {
+	p, release := newBlockedPool(t, 0)
+
+	var (
+		mu    sync.Mutex
+		order []Priority
+	)
+	n := 10
+	for _, pr := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		pr := pr
+		for i := 0; i < n; i++ {
+			require.NoError(t, p.GoWithPriority(pr, func() {
+				mu.Lock()
+				order = append(order, pr)
+				mu.Unlock()
+			}))
+		}
+	}
+
+	s := p.Stats()
+	require.Equal(t, 3*n, s.Queued)
+	require.Equal(t, n, s.Priorities[PriorityLow].Queued)
+	require.Equal(t, uint64(n+1), s.Priorities[PriorityNormal].Submitted)
+
+	close(release)
+	_, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.Len(t, order, 3*n)
+
+	// while all levels are busy, every round of 7 picks follows the 4:2:1 weights
+	count := map[Priority]int{}
+	for _, pr := range order[:7] {
+		count[pr]++
+	}
+	require.Equal(t, map[Priority]int{PriorityHigh: 4, PriorityNormal: 2, PriorityLow: 1}, count)
+	// so high priority tasks finish first, but low priority ones aren't starved
+	require.Equal(t, PriorityLow, order[len(order)-1])
+
+	s = p.Stats()
+	for _, pr := range []Priority{PriorityLow, PriorityHigh} {
+		require.Equal(t, uint64(n), s.Priorities[pr].Completed)
+		require.Equal(t, uint64(n), s.Priorities[pr].QueueWait.Count)
+	}
+}
+
+func TestSubmit_Priority(t *testing.T) {
+	p := NewGoPool("TestSubmit_Priority", nil)
+
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		return 1, nil
+	}, WithPriority(PriorityHigh))
+	_, err := f.Get(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), p.Stats().Priorities[PriorityHigh].Submitted)
+}
@@ -0,0 +1,133 @@
+package concurrency
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mateothegreat/util/attempt"
+)
+
+// Clock is the source of time used by RateLimiter, shared with attempt.Retrier.
+// Use attempt.FakeClock in tests that must not depend on the wall clock.
+type Clock = attempt.Clock
+
+// RateLimiter is a token bucket: it holds up to Burst tokens, refilled at Rate tokens per
+// second, and every call that goes through takes one.
+//
+// Set Option.RateLimiter to cap how fast a GoPool starts tasks, whatever its concurrency.
+// A RateLimiter can be shared by several pools, and used on its own. It is safe for
+// concurrent use.
+type RateLimiter struct {
+	// Rate is the number of tokens added per second. Values <= 0 mean no limit.
+	Rate float64
+
+	// Burst is the bucket size, how many calls may go through at once after a pause.
+	// Values below 1 are treated as 1.
+	Burst int
+
+	// Clock is the source of time. Nil means attempt.RealClock.
+	Clock Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	primed bool
+}
+
+// NewRateLimiter creates a RateLimiter allowing rate calls per second on average, and up to
+// burst at once. Its bucket starts full.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{Rate: rate, Burst: burst}
+}
+
+// Allow takes a token if one is available and reports whether it did.
+func (l *RateLimiter) Allow() bool {
+	if l.Rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Wait blocks until a token is available and takes it, or returns ctx.Err() if ctx is done
+// first. Waiters are served in the order they called Wait.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.Rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	l.refill()
+	// take the token now, going into debt if needed, so that later callers queue up behind
+	l.tokens--
+	wait := time.Duration(math.Ceil(-l.tokens / l.Rate * float64(time.Second)))
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-l.clock().After(wait):
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++ // give it back
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Tokens returns the number of tokens currently available. It's negative while callers of
+// Wait are queued up.
+func (l *RateLimiter) Tokens() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	return l.tokens
+}
+
+// refill adds the tokens earned since the last call. l.mu must be held.
+func (l *RateLimiter) refill() {
+	now := l.clock().Now()
+	burst := float64(max(l.Burst, 1))
+	if !l.primed {
+		l.primed = true
+		l.tokens = burst
+		l.last = now
+		return
+	}
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(burst, l.tokens+elapsed.Seconds()*l.Rate)
+		l.last = now
+	}
+}
+
+func (l *RateLimiter) clock() Clock {
+	if l.Clock == nil {
+		return attempt.RealClock
+	}
+	return l.Clock
+}
+
+// throttle waits for the pool's RateLimiter, if any, before a task starts.
+func (p *GoPool) throttle(ctx context.Context) error {
+	if p.limiter == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return p.limiter.Wait(ctx)
+}
@@ -0,0 +1,107 @@
+package concurrency
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mateothegreat/util/attempt"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	clock := attempt.NewFakeClock(time.Unix(0, 0))
+	l := NewRateLimiter(2, 3)
+	l.Clock = clock
+
+	for i := 0; i < 3; i++ {
+		require.True(t, l.Allow())
+	}
+	require.False(t, l.Allow())
+
+	clock.Advance(time.Second)
+	require.True(t, l.Allow())
+	require.True(t, l.Allow())
+	require.False(t, l.Allow())
+
+	// the bucket doesn't fill beyond Burst
+	clock.Advance(time.Hour)
+	require.Equal(t, float64(3), l.Tokens())
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	clock := attempt.NewFakeClock(time.Unix(0, 0))
+	l := &RateLimiter{Rate: 2, Burst: 1, Clock: clock}
+
+	require.NoError(t, l.Wait(context.Background()))
+
+	done := make(chan error, 1)
+	go func() { done <- l.Wait(context.Background()) }()
+	clock.BlockUntil(1)
+	require.Equal(t, float64(-1), l.Tokens())
+
+	clock.Advance(400 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Wait returned early")
+	default:
+	}
+	clock.Advance(100 * time.Millisecond)
+	require.NoError(t, <-done)
+	require.False(t, l.Allow())
+}
+
+func TestRateLimiter_WaitCancel(t *testing.T) {
+	clock := attempt.NewFakeClock(time.Unix(0, 0))
+	l := &RateLimiter{Rate: 1, Burst: 1, Clock: clock}
+	require.True(t, l.Allow())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Wait(ctx) }()
+	clock.BlockUntil(1)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// the token reserved by the cancelled Wait was given back
+	clock.Advance(time.Second)
+	require.True(t, l.Allow())
+
+	require.ErrorIs(t, l.Wait(ctx), context.Canceled)
+}
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	l := NewRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		require.True(t, l.Allow())
+		require.NoError(t, l.Wait(context.Background()))
+	}
+}
+
+func TestGoPool_RateLimiter(t *testing.T) {
+	clock := attempt.NewFakeClock(time.Unix(0, 0))
+	o := DefaultOption()
+	o.RateLimiter = &RateLimiter{Rate: 1, Burst: 2, Clock: clock}
+	p := NewGoPool("TestGoPool_RateLimiter", o)
+
+	var ran int32
+	for i := 0; i < 4; i++ {
+		require.NoError(t, p.Go(func() { atomic.AddInt32(&ran, 1) }))
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&ran) == 2 }, time.Second, time.Millisecond)
+
+	clock.BlockUntil(2)
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&ran) == 3 }, time.Second, time.Millisecond)
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&ran) == 4 }, time.Second, time.Millisecond)
+
+	// keyed tasks take one token each
+	k := NewKeyedPool(p, 0)
+	require.NoError(t, k.GoKey("a", func() { atomic.AddInt32(&ran, 1) }))
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&ran) == 5 }, time.Second, time.Millisecond)
+}
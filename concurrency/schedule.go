@@ -0,0 +1,325 @@
+package concurrency
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// OverlapPolicy decides what GoEvery does when a run is due while the previous one is still going.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the run. It's the default.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue starts the run once the previous one finished. At most one run waits,
+	// further ones are skipped.
+	OverlapQueue
+	// OverlapConcurrent starts the run regardless.
+	OverlapConcurrent
+)
+
+func (o OverlapPolicy) String() string {
+	switch o {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	case OverlapConcurrent:
+		return "concurrent"
+	default:
+		return "unknown"
+	}
+}
+
+// ScheduledTask is a task started with GoAfter, GoAt or GoEvery.
+type ScheduledTask struct {
+	p        *GoPool
+	f        func()
+	states   []interface{}
+	interval time.Duration
+	policy   OverlapPolicy
+
+	// guarded by p.sched.mu
+	at    time.Time
+	index int // in the scheduler's heap, -1 if not in it
+
+	mu       sync.Mutex
+	running  int
+	pending  bool
+	stopped  bool
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// Cancel stops the task from running again and reports whether it was still scheduled.
+// A run in progress is not interrupted.
+func (s *ScheduledTask) Cancel() bool {
+	s.mu.Lock()
+	wasStopped := s.stopped
+	s.stopped = true
+	s.pending = false
+	idle := s.running == 0
+	s.mu.Unlock()
+
+	s.p.sched.remove(s)
+	if idle {
+		s.finish()
+	}
+	return !wasStopped
+}
+
+// Done returns a channel that is closed once the task won't run anymore: after it was
+// cancelled and its last run finished, or after the single run of GoAfter and GoAt.
+func (s *ScheduledTask) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *ScheduledTask) finish() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+// GoAfter runs f on the pool once d has elapsed.
+func (p *GoPool) GoAfter(d time.Duration, f func()) (*ScheduledTask, error) {
+	return p.scheduleAt(time.Now().Add(d), 0, OverlapSkip, f)
+}
+
+// GoAt runs f on the pool at t.
+func (p *GoPool) GoAt(t time.Time, f func()) (*ScheduledTask, error) {
+	return p.scheduleAt(t, 0, OverlapSkip, f)
+}
+
+// GoEvery runs f on the pool every interval, starting one interval from now, until the
+// returned task is cancelled or the pool shuts down. Runs missed while the pool was busy
+// are skipped rather than run in a burst. policy decides what happens when a run is due
+// while the previous one is still going, OverlapSkip by default.
+//
+// All scheduled tasks of a pool share a single timer goroutine, which queues the runs
+// that are due like TryGo does: it never waits for room nor applies the OverflowPolicy,
+// so a run that finds the queue full is missed, as is the single run of GoAfter and GoAt.
+func (p *GoPool) GoEvery(interval time.Duration, f func(), policy ...OverlapPolicy) (*ScheduledTask, error) {
+	if interval <= 0 {
+		panic("gopool: non-positive interval for GoEvery")
+	}
+	pol := OverlapSkip
+	if len(policy) > 0 {
+		pol = policy[0]
+	}
+	return p.scheduleAt(time.Now().Add(interval), interval, pol, f)
+}
+
+func (p *GoPool) scheduleAt(at time.Time, interval time.Duration, policy OverlapPolicy, f func()) (*ScheduledTask, error) {
+	s := &ScheduledTask{
+		p:        p,
+		f:        f,
+		states:   p.capture(context.Background()),
+		interval: interval,
+		policy:   policy,
+		at:       at,
+		index:    -1,
+		done:     make(chan struct{}),
+	}
+	if err := p.sched.add(p, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// fire submits a run of s to the pool, as its OverlapPolicy allows.
+func (s *ScheduledTask) fire() {
+	s.mu.Lock()
+	switch {
+	case s.stopped:
+		s.mu.Unlock()
+		return
+	case s.running > 0 && s.policy == OverlapSkip:
+		s.mu.Unlock()
+		return
+	case s.running > 0 && s.policy == OverlapQueue:
+		s.pending = true
+		s.mu.Unlock()
+		return
+	}
+	s.running++
+	s.mu.Unlock()
+
+	s.submit()
+}
+
+// submit queues a run of s without blocking, so that a full pool never holds up the timer
+// goroutine nor makes it run the task itself.
+func (s *ScheduledTask) submit() {
+	t := newTask(context.Background(), nil, nil)
+	t.states = s.states
+	// a run that panics is over once the pool gave up restarting it
+	t.f = func() {
+		s.f()
+		s.ran(nil)
+	}
+	t.gaveUp = func() { s.ran(nil) }
+	t.dropped = s.ran
+	if queued, err := s.p.enqueue(t, false); !queued {
+		s.ran(err)
+		return
+	}
+	s.p.wakeWorker()
+}
+
+// ran is called after a run finished, or failed to start with err. A periodic task skips
+// the run if the pool was full, and stops once the pool is closed.
+func (s *ScheduledTask) ran(err error) {
+	s.mu.Lock()
+	if errors.Is(err, ErrPoolClosed) || s.interval == 0 {
+		s.stopped = true
+	}
+	if s.pending && !s.stopped {
+		s.pending = false
+		s.mu.Unlock()
+		s.submit()
+		return
+	}
+	s.running--
+	finished := s.stopped && s.running == 0
+	s.mu.Unlock()
+
+	if finished {
+		s.p.sched.remove(s)
+		s.finish()
+	}
+}
+
+// scheduler keeps a pool's scheduled tasks in a heap ordered by their next run, and runs a
+// goroutine sleeping until the earliest is due.
+type scheduler struct {
+	mu      sync.Mutex
+	tasks   scheduleHeap
+	wake    chan struct{}
+	started bool
+	stopped bool
+}
+
+func (sc *scheduler) add(p *GoPool, s *ScheduledTask) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	select {
+	case <-p.closing:
+		return ErrPoolClosed
+	default:
+	}
+	if sc.stopped {
+		return ErrPoolClosed
+	}
+
+	if !sc.started {
+		sc.started = true
+		sc.wake = make(chan struct{}, 1)
+		go sc.run(p)
+	}
+	heap.Push(&sc.tasks, s)
+	if s.index == 0 {
+		sc.notify()
+	}
+	return nil
+}
+
+func (sc *scheduler) remove(s *ScheduledTask) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if s.index >= 0 {
+		heap.Remove(&sc.tasks, s.index)
+	}
+}
+
+// notify wakes up run to look at the heap again. sc.mu must be held.
+func (sc *scheduler) notify() {
+	select {
+	case sc.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (sc *scheduler) run(p *GoPool) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	var due []*ScheduledTask
+	for {
+		sc.mu.Lock()
+		now := time.Now()
+		due = due[:0]
+		for len(sc.tasks) > 0 && !sc.tasks[0].at.After(now) {
+			s := sc.tasks[0]
+			due = append(due, s)
+			if s.interval > 0 {
+				// next tick after now, skipping the ones missed
+				missed := now.Sub(s.at) / s.interval
+				s.at = s.at.Add((missed + 1) * s.interval)
+				heap.Fix(&sc.tasks, 0)
+			} else {
+				heap.Pop(&sc.tasks)
+			}
+		}
+		wait := time.Duration(-1)
+		if len(sc.tasks) > 0 {
+			wait = sc.tasks[0].at.Sub(now)
+		}
+		sc.mu.Unlock()
+
+		for _, s := range due {
+			s.fire()
+		}
+
+		var timerC <-chan time.Time
+		if wait >= 0 {
+			timer.Reset(wait)
+			timerC = timer.C
+		}
+		select {
+		case <-timerC:
+		case <-sc.wake:
+		case <-p.closing:
+			sc.stop()
+			return
+		}
+	}
+}
+
+// stop cancels all scheduled tasks when the pool shuts down.
+func (sc *scheduler) stop() {
+	sc.mu.Lock()
+	sc.stopped = true
+	tasks := append([]*ScheduledTask(nil), sc.tasks...)
+	sc.mu.Unlock()
+	for _, s := range tasks {
+		s.Cancel()
+	}
+}
+
+// scheduleHeap implements heap.Interface, ordered by ScheduledTask.at.
+type scheduleHeap []*ScheduledTask
+
+func (h scheduleHeap) Len() int           { return len(h) }
+func (h scheduleHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x interface{}) {
+	s := x.(*ScheduledTask)
+	s.index = len(*h)
+	*h = append(*h, s)
+}
+
+func (h *scheduleHeap) Pop() interface{} {
+	old := *h
+	s := old[len(old)-1]
+	old[len(old)-1] = nil
+	s.index = -1
+	*h = old[:len(old)-1]
+	return s
+}
@@ -0,0 +1,172 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func waitDone(t *testing.T, s *ScheduledTask) {
+	t.Helper()
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduled task not done")
+	}
+}
+
+func TestGoPool_GoAfter(t *testing.T) {
+	p := NewGoPool("TestGoPool_GoAfter", nil)
+
+	start := time.Now()
+	var ranAt atomic.Value
+	s, err := p.GoAfter(20*time.Millisecond, func() { ranAt.Store(time.Now()) })
+	require.NoError(t, err)
+	waitDone(t, s)
+	require.GreaterOrEqual(t, ranAt.Load().(time.Time).Sub(start), 20*time.Millisecond)
+	require.False(t, s.Cancel())
+}
+
+func TestGoPool_GoAtOrder(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 1 // runs in the order they were submitted
+	p := NewGoPool("TestGoPool_GoAtOrder", o)
+
+	var (
+		mu    sync.Mutex
+		order []int
+		tasks []*ScheduledTask
+	)
+	now := time.Now()
+	for i := 9; i >= 0; i-- {
+		i := i
+		s, err := p.GoAt(now.Add(time.Duration(i)*5*time.Millisecond), func() {
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		})
+		require.NoError(t, err)
+		tasks = append(tasks, s)
+	}
+	for _, s := range tasks {
+		waitDone(t, s)
+	}
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, order)
+}
+
+func TestGoPool_ScheduleCancel(t *testing.T) {
+	p := NewGoPool("TestGoPool_ScheduleCancel", nil)
+
+	var ran int32
+	s, err := p.GoAfter(10*time.Millisecond, func() { atomic.StoreInt32(&ran, 1) })
+	require.NoError(t, err)
+	require.True(t, s.Cancel())
+	require.False(t, s.Cancel())
+	waitDone(t, s)
+
+	time.Sleep(20 * time.Millisecond)
+	require.Zero(t, atomic.LoadInt32(&ran))
+}
+
+func TestGoPool_GoEvery(t *testing.T) {
+	p := NewGoPool("TestGoPool_GoEvery", nil)
+
+	var n int32
+	s, err := p.GoEvery(time.Millisecond, func() { atomic.AddInt32(&n, 1) })
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&n) >= 5 }, 5*time.Second, time.Millisecond)
+	require.True(t, s.Cancel())
+	waitDone(t, s)
+
+	after := atomic.LoadInt32(&n)
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, after, atomic.LoadInt32(&n))
+	require.Panics(t, func() { p.GoEvery(0, func() {}) })
+}
+
+func TestGoPool_GoEveryOverlap(t *testing.T) {
+	for _, policy := range []OverlapPolicy{OverlapSkip, OverlapQueue, OverlapConcurrent} {
+		t.Run(policy.String(), func(t *testing.T) {
+			p := NewGoPool(t.Name(), nil)
+
+			var runs, running, peak int32
+			s, err := p.GoEvery(time.Millisecond, func() {
+				atomic.AddInt32(&runs, 1)
+				cur := atomic.AddInt32(&running, 1)
+				for {
+					old := atomic.LoadInt32(&peak)
+					if cur <= old || atomic.CompareAndSwapInt32(&peak, old, cur) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+			}, policy)
+			require.NoError(t, err)
+			require.Eventually(t, func() bool { return atomic.LoadInt32(&runs) >= 3 }, 5*time.Second, time.Millisecond)
+			s.Cancel()
+			waitDone(t, s)
+
+			require.Zero(t, atomic.LoadInt32(&running))
+			if policy == OverlapConcurrent {
+				require.Greater(t, atomic.LoadInt32(&peak), int32(1))
+			} else {
+				require.Equal(t, int32(1), atomic.LoadInt32(&peak))
+			}
+		})
+	}
+}
+
+func TestGoPool_ScheduleShutdown(t *testing.T) {
+	p := NewGoPool("TestGoPool_ScheduleShutdown", nil)
+
+	once, err := p.GoAfter(time.Hour, func() {})
+	require.NoError(t, err)
+	every, err := p.GoEvery(time.Hour, func() {})
+	require.NoError(t, err)
+
+	_, err = p.Shutdown(context.Background())
+	require.NoError(t, err)
+	waitDone(t, once)
+	waitDone(t, every)
+
+	_, err = p.GoAfter(time.Millisecond, func() {})
+	require.ErrorIs(t, err, ErrPoolClosed)
+}
+
+func TestGoPool_ScheduleFullPool(t *testing.T) {
+	p, release := newBlockedPool(t, 1)
+	defer close(release)
+
+	// A full pool misses the run instead of blocking the timer goroutine.
+	var ran int32
+	missed, err := p.GoAfter(10*time.Millisecond, func() { atomic.StoreInt32(&ran, 1) })
+	require.NoError(t, err)
+	start := time.Now()
+	next, err := p.GoAfter(20*time.Millisecond, func() {})
+	require.NoError(t, err)
+	waitDone(t, missed)
+	waitDone(t, next)
+	require.Less(t, time.Since(start), time.Second)
+	require.Zero(t, atomic.LoadInt32(&ran))
+}
+
+func TestGoPool_GoEveryPanic(t *testing.T) {
+	o := DefaultOption()
+	o.OnPanic = func(r *PanicReport) {}
+	p := NewGoPool("TestGoPool_GoEveryPanic", o)
+
+	var n int32
+	s, err := p.GoEvery(time.Millisecond, func() {
+		atomic.AddInt32(&n, 1)
+		panic("boom")
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&n) >= 3 }, 5*time.Second, time.Millisecond)
+	require.True(t, s.Cancel())
+	waitDone(t, s)
+}
@@ -0,0 +1,77 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrPoolClosed is returned for tasks submitted after Shutdown or ShutdownNow.
+var ErrPoolClosed = errors.New("gopool: pool is closed")
+
+// Shutdown stops the pool from accepting tasks and waits for queued and running tasks
+// to finish. If ctx is done first, it drops the tasks still queued and returns how many
+// tasks were abandoned, queued or still running, along with ctx.Err().
+//
+// Shutdown may be called more than once, e.g. after a first call timed out.
+func (p *GoPool) Shutdown(ctx context.Context) (abandoned int, err error) {
+	p.close()
+
+	// make sure someone picks up what's queued, e.g. after all workers aged out
+	if p.queued() > 0 {
+		p.spawnWorker()
+	}
+
+	select {
+	case <-p.drained:
+		return 0, nil
+	case <-ctx.Done():
+		_, dropped := p.dropQueued()
+		return dropped + int(atomic.LoadInt64(&p.pending)), ctx.Err()
+	}
+}
+
+// ShutdownNow stops the pool from accepting tasks and returns the queued tasks that
+// haven't started, without running them. Running tasks are not interrupted.
+//
+// Tasks that report their own outcome, such as those of Submit, Group, Map, KeyedPool and
+// GoEvery, are not returned: they fail with ErrPoolClosed instead, so that running the
+// returned funcs never completes a task twice.
+func (p *GoPool) ShutdownNow() []func() {
+	atomic.StoreInt32(&p.aborted, 1)
+	p.close()
+	fs, _ := p.dropQueued()
+	return fs
+}
+
+// close marks the pool closed and wakes up workers, the ticker and blocked submitters.
+func (p *GoPool) close() {
+	p.closingOnce.Do(func() { close(p.closing) })
+
+	p.mu.Lock()
+	atomic.StoreInt32(&p.closed, 1)
+	p.mu.Unlock()
+
+	if atomic.LoadInt64(&p.pending) == 0 {
+		p.drainedOnce.Do(func() { close(p.drained) })
+	}
+}
+
+// dropQueued empties the task queue and returns the funcs of the dropped tasks that have
+// no dropped callback, along with how many tasks it dropped. The others, e.g. Futures,
+// fail with ErrPoolClosed.
+func (p *GoPool) dropQueued() (fs []func(), n int) {
+	for {
+		t, ok := p.next()
+		if !ok {
+			return fs, n
+		}
+		if t.f != nil {
+			n++
+			if t.dropped == nil {
+				fs = append(fs, t.f)
+			}
+		}
+		p.dropTask(t, ErrPoolClosed)
+	}
+}
@@ -0,0 +1,131 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoPool_Shutdown(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 2
+	p := NewGoPool("TestGoPool_Shutdown", o)
+
+	var v int32
+	n := 100
+	for i := 0; i < n; i++ {
+		require.NoError(t, p.Go(func() {
+			time.Sleep(100 * time.Microsecond)
+			atomic.AddInt32(&v, 1)
+		}))
+	}
+
+	abandoned, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, abandoned)
+	require.Equal(t, int32(n), atomic.LoadInt32(&v))
+
+	require.True(t, errors.Is(p.Go(func() {}), ErrPoolClosed))
+	require.False(t, p.TryGo(func() {}))
+
+	// workers and ticker exit
+	require.Eventually(t, func() bool { return p.CurrentWorkers() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestGoPool_ShutdownTimeout(t *testing.T) {
+	p, release := newBlockedPool(t, 0)
+	defer close(release)
+
+	var ran int32
+	for i := 0; i < 3; i++ {
+		p.Go(func() { atomic.AddInt32(&ran, 1) })
+	}
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) { return 1, nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	abandoned, err := p.Shutdown(ctx)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.Equal(t, 5, abandoned) // 4 queued + 1 running
+
+	_, err = f.Get(context.Background())
+	require.True(t, errors.Is(err, ErrPoolClosed))
+	require.Zero(t, atomic.LoadInt32(&ran))
+}
+
+func TestGoPool_ShutdownNow(t *testing.T) {
+	p, release := newBlockedPool(t, 0)
+
+	var ran int32
+	for i := 0; i < 3; i++ {
+		p.Go(func() { atomic.AddInt32(&ran, 1) })
+	}
+
+	unstarted := p.ShutdownNow()
+	close(release)
+	require.Len(t, unstarted, 3)
+	require.True(t, errors.Is(p.Go(func() {}), ErrPoolClosed))
+
+	// the running task finished, the queued ones never ran
+	abandoned, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, abandoned)
+	require.Zero(t, atomic.LoadInt32(&ran))
+
+	for _, f := range unstarted {
+		f()
+	}
+	require.Equal(t, int32(3), atomic.LoadInt32(&ran))
+}
+
+func TestGoPool_ShutdownNowSkipsWrappers(t *testing.T) {
+	p, release := newBlockedPool(t, 0)
+	defer close(release)
+
+	var ran int32
+	require.NoError(t, p.Go(func() { atomic.AddInt32(&ran, 1) }))
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&ran, 1)
+		return 1, nil
+	})
+	g, _ := NewGroup(context.Background(), p)
+	g.Go(func(ctx context.Context) error {
+		atomic.AddInt32(&ran, 1)
+		return nil
+	})
+
+	// only the plain task is handed back, the others fail on their own
+	unstarted := p.ShutdownNow()
+	require.Len(t, unstarted, 1)
+	for _, f := range unstarted {
+		f()
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&ran))
+
+	_, err := f.Get(context.Background())
+	require.ErrorIs(t, err, ErrPoolClosed)
+	require.ErrorIs(t, g.Wait(), ErrPoolClosed)
+}
+
+func TestGoPool_ShutdownUnblocksSubmitters(t *testing.T) {
+	p, release := newBlockedPool(t, 1, withOverflow(OverflowBlock))
+	defer close(release)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Go(func() {})
+	}()
+	time.Sleep(10 * time.Millisecond) // let it block on the full queue
+
+	p.ShutdownNow()
+	select {
+	case err := <-errc:
+		require.True(t, errors.Is(err, ErrPoolClosed))
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked submitter not released")
+	}
+}
@@ -0,0 +1,202 @@
+package concurrency
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// histogramBounds are the upper bounds of the buckets of the pool's latency histograms.
+var histogramBounds = [...]time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Stats is a point-in-time snapshot of a GoPool.
+//
+// Counters are read one by one without stopping the pool, so under load they may be
+// slightly inconsistent with each other.
+type Stats struct {
+	Name string
+
+	// Workers is the number of pool workers, Running the number of tasks running,
+	// including overflow goroutines, and Idle the number of workers waiting for a task.
+	// Limit is the worker cap in effect, 0 if there's none.
+	Workers int
+	Limit   int
+	Running int
+	Idle    int
+
+	// Queued is the number of tasks waiting in the queue.
+	Queued int
+
+	// Submitted counts accepted tasks, Rejected the ones refused with an error.
+	Submitted uint64
+	Rejected  uint64
+
+	// Completed counts tasks that ran, including the Panicked ones.
+	Completed uint64
+	Panicked  uint64
+
+	// Dropped counts accepted tasks discarded without running, see Option.OnDropped.
+	Dropped uint64
+
+	// Overflowed counts goroutines spawned outside the pool by OverflowSpawn.
+	Overflowed uint64
+
+	// QueueWait is the time from submission until a task starts, ExecTime how long it ran.
+	QueueWait Histogram
+	ExecTime  Histogram
+
+	// Priorities breaks the queue down by priority level.
+	Priorities map[Priority]PriorityStats
+}
+
+// PriorityStats is the part of Stats for a single priority level.
+type PriorityStats struct {
+	Queued    int
+	Submitted uint64
+	Completed uint64
+	Dropped   uint64
+	QueueWait Histogram
+}
+
+// Histogram is a snapshot of a latency distribution.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets. Counts has an entry per bucket,
+	// plus a last one for observations above the last bound. Counts are not cumulative.
+	Bounds []time.Duration
+	Counts []uint64
+
+	// Count is the number of observations and Sum their total.
+	Count uint64
+	Sum   time.Duration
+}
+
+// Mean returns the average observation, or zero if there are none.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// StatsExporter receives the pool's observations as they happen, e.g. to feed
+// Prometheus-style histograms with buckets of their own. Counters and gauges can be
+// collected by calling Stats when scraped.
+//
+// Its methods are called on the goroutine that ran the task, so they must be fast and
+// safe for concurrent use.
+type StatsExporter interface {
+	// ObserveQueueWait is called with the time a task waited before it started.
+	ObserveQueueWait(pool string, d time.Duration)
+
+	// ObserveExec is called with the time a task ran, and whether it panicked.
+	ObserveExec(pool string, d time.Duration, panicked bool)
+}
+
+type histogram struct {
+	counts [len(histogramBounds) + 1]uint64
+	sum    int64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(histogramBounds) && d > histogramBounds[i] {
+		i++
+	}
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Bounds: histogramBounds[:],
+		Counts: make([]uint64, len(h.counts)),
+		Sum:    time.Duration(atomic.LoadInt64(&h.sum)),
+	}
+	for i := range h.counts {
+		s.Counts[i] = atomic.LoadUint64(&h.counts[i])
+		s.Count += s.Counts[i]
+	}
+	return s
+}
+
+type poolStats struct {
+	running    int32
+	busy       int32 // workers running a task
+	submitted  uint64
+	rejected   uint64
+	completed  uint64
+	panicked   uint64
+	dropped    uint64
+	overflowed uint64
+
+	queueWait histogram
+	execTime  histogram
+
+	levels [numPriorities]levelStats
+}
+
+type levelStats struct {
+	submitted uint64
+	completed uint64
+	dropped   uint64
+	queueWait histogram
+}
+
+func (s *poolStats) submit(t task) {
+	atomic.AddUint64(&s.submitted, 1)
+	atomic.AddUint64(&s.levels[t.priority.level()].submitted, 1)
+}
+
+// Stats returns a snapshot of the pool's counters and latency histograms.
+func (p *GoPool) Stats() Stats {
+	workers := p.CurrentWorkers()
+	idle := workers - int(atomic.LoadInt32(&p.stats.busy))
+	if idle < 0 {
+		idle = 0
+	}
+	s := Stats{
+		Name:       p.name,
+		Workers:    workers,
+		Limit:      int(atomic.LoadInt32(&p.maxWorkers)),
+		Running:    int(atomic.LoadInt32(&p.stats.running)),
+		Idle:       idle,
+		Queued:     p.queued(),
+		Submitted:  atomic.LoadUint64(&p.stats.submitted),
+		Rejected:   atomic.LoadUint64(&p.stats.rejected),
+		Completed:  atomic.LoadUint64(&p.stats.completed),
+		Panicked:   atomic.LoadUint64(&p.stats.panicked),
+		Dropped:    atomic.LoadUint64(&p.stats.dropped),
+		Overflowed: atomic.LoadUint64(&p.stats.overflowed),
+		QueueWait:  p.stats.queueWait.snapshot(),
+		ExecTime:   p.stats.execTime.snapshot(),
+		Priorities: make(map[Priority]PriorityStats, numPriorities),
+	}
+	for pr := PriorityHigh; pr >= PriorityLow; pr-- {
+		level := &p.stats.levels[pr.level()]
+		s.Priorities[pr] = PriorityStats{
+			Queued:    len(p.queues[pr.level()]),
+			Submitted: atomic.LoadUint64(&level.submitted),
+			Completed: atomic.LoadUint64(&level.completed),
+			Dropped:   atomic.LoadUint64(&level.dropped),
+			QueueWait: level.queueWait.snapshot(),
+		}
+	}
+	return s
+}
@@ -0,0 +1,110 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testExporter struct {
+	mu       sync.Mutex
+	waits    int
+	execs    int
+	panicked int
+}
+
+func (e *testExporter) ObserveQueueWait(pool string, d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.waits++
+}
+
+func (e *testExporter) ObserveExec(pool string, d time.Duration, panicked bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.execs++
+	if panicked {
+		e.panicked++
+	}
+}
+
+func TestGoPool_Stats(t *testing.T) {
+	exporter := &testExporter{}
+	p, release := newBlockedPool(t, 0, withOverflow(OverflowReject), func(o *Option) {
+		o.TaskChanBuffer = 2
+		o.Exporter = exporter
+	})
+	p.SetPanicHandler(func(ctx context.Context, r interface{}) {})
+	require.NoError(t, p.Go(func() { panic("boom") }))
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, p.CtxGo(ctx, func() {}))
+	cancel()
+	require.ErrorIs(t, p.Go(func() {}), ErrPoolFull)
+
+	s := p.Stats()
+	require.Equal(t, "TestGoPool_Stats", s.Name)
+	require.Equal(t, 1, s.Workers)
+	require.Equal(t, 1, s.Running)
+	require.Equal(t, 0, s.Idle)
+	require.Equal(t, 2, s.Queued)
+	require.Equal(t, uint64(3), s.Submitted)
+	require.Equal(t, uint64(1), s.Rejected)
+
+	time.Sleep(time.Millisecond) // counted in the blocked task's exec time
+	close(release)
+	_, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+
+	s = p.Stats()
+	require.Equal(t, 0, s.Running)
+	require.Equal(t, 0, s.Queued)
+	require.Equal(t, uint64(2), s.Completed)
+	require.Equal(t, uint64(1), s.Panicked)
+	require.Equal(t, uint64(1), s.Dropped)
+	require.Equal(t, uint64(2), s.ExecTime.Count)
+	require.Equal(t, uint64(2), s.QueueWait.Count)
+	require.GreaterOrEqual(t, s.ExecTime.Sum, time.Millisecond)
+	require.Len(t, s.ExecTime.Counts, len(s.ExecTime.Bounds)+1)
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	require.Equal(t, 2, exporter.waits)
+	require.Equal(t, 2, exporter.execs)
+	require.Equal(t, 1, exporter.panicked)
+}
+
+func TestGoPool_StatsOverflow(t *testing.T) {
+	o := DefaultOption()
+	o.TaskChanBuffer = 0
+	p := NewGoPool("TestGoPool_StatsOverflow", o)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, p.Go(wg.Done))
+	}
+	wg.Wait()
+
+	s := p.Stats()
+	require.Equal(t, uint64(3), s.Submitted)
+	require.Equal(t, uint64(3), s.Overflowed)
+	require.Eventually(t, func() bool { return p.Stats().Completed == 3 }, time.Second, time.Millisecond)
+}
+
+func TestHistogram(t *testing.T) {
+	var h histogram
+	h.observe(0)
+	h.observe(time.Millisecond)
+	h.observe(time.Hour)
+
+	s := h.snapshot()
+	require.Equal(t, uint64(3), s.Count)
+	require.Equal(t, uint64(1), s.Counts[0])
+	require.Equal(t, uint64(1), s.Counts[4]) // bounds are inclusive
+	require.Equal(t, uint64(1), s.Counts[len(s.Counts)-1])
+	require.Equal(t, (time.Hour+time.Millisecond)/3, s.Mean())
+	require.Zero(t, Histogram{}.Mean())
+}
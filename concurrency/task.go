@@ -0,0 +1,48 @@
+package concurrency
+
+import (
+	"context"
+	"time"
+)
+
+// TaskOption configures a single task passed to CtxGo, TryCtxGo or Submit.
+type TaskOption func(t *task)
+
+// WithDeadline drops the task instead of running it if it hasn't started by d.
+// Time spent waiting in the queue counts against it. The task is dropped with
+// context.DeadlineExceeded.
+func WithDeadline(d time.Time) TaskOption {
+	return func(t *task) {
+		if t.deadline.IsZero() || d.Before(t.deadline) {
+			t.deadline = d
+		}
+	}
+}
+
+// WithTimeout is like WithDeadline, with the deadline d after the task is submitted.
+func WithTimeout(d time.Duration) TaskOption {
+	return func(t *task) {
+		WithDeadline(time.Now().Add(d))(t)
+	}
+}
+
+func newTask(ctx context.Context, f func(), opts []TaskOption) task {
+	t := task{ctx: ctx, f: f, submitted: time.Now()}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	return t
+}
+
+// expired returns why t must not start anymore, or nil if it may run.
+func (t *task) expired() error {
+	if t.ctx != nil {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if !t.deadline.IsZero() && !time.Now().Before(t.deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
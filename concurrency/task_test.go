@@ -0,0 +1,99 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type droppedRecorder struct {
+	mu      sync.Mutex
+	reasons []error
+}
+
+func (r *droppedRecorder) record(ctx context.Context, reason error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, reason)
+}
+
+func (r *droppedRecorder) get() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]error(nil), r.reasons...)
+}
+
+func TestGoPool_SkipCancelled(t *testing.T) {
+	dropped := &droppedRecorder{}
+	p, release := newBlockedPool(t, 0, withDropped(dropped))
+
+	var ran int32
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, p.CtxGo(ctx, func() { atomic.AddInt32(&ran, 1) }))
+	require.NoError(t, p.Go(func() { atomic.AddInt32(&ran, 10) }))
+	cancel()
+
+	close(release)
+	_, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int32(10), atomic.LoadInt32(&ran))
+	require.Equal(t, []error{context.Canceled}, dropped.get())
+}
+
+func TestGoPool_TaskDeadline(t *testing.T) {
+	dropped := &droppedRecorder{}
+	p, release := newBlockedPool(t, 0, withDropped(dropped))
+
+	var ran int32
+	require.NoError(t, p.CtxGo(context.Background(), func() { atomic.AddInt32(&ran, 1) }, WithTimeout(time.Millisecond)))
+	require.NoError(t, p.CtxGo(context.Background(), func() { atomic.AddInt32(&ran, 10) }, WithTimeout(time.Hour)))
+	require.NoError(t, p.CtxGo(context.Background(), func() { atomic.AddInt32(&ran, 100) },
+		WithTimeout(time.Hour), WithDeadline(time.Now().Add(-time.Second))))
+	time.Sleep(5 * time.Millisecond) // queue wait counts against the deadline
+
+	close(release)
+	_, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int32(10), atomic.LoadInt32(&ran))
+	require.Equal(t, []error{context.DeadlineExceeded, context.DeadlineExceeded}, dropped.get())
+}
+
+func TestGoPool_OnDroppedShutdown(t *testing.T) {
+	dropped := &droppedRecorder{}
+	p, release := newBlockedPool(t, 0, withDropped(dropped))
+	defer close(release)
+
+	require.NoError(t, p.Go(func() {}))
+	require.Len(t, p.ShutdownNow(), 1)
+	require.Equal(t, []error{ErrPoolClosed}, dropped.get())
+}
+
+func TestSubmit_Deadline(t *testing.T) {
+	dropped := &droppedRecorder{}
+	p, release := newBlockedPool(t, 0, withDropped(dropped))
+
+	f := Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		return 1, nil
+	}, WithTimeout(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	close(release)
+
+	_, err := f.Get(context.Background())
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.Eventually(t, func() bool { return len(dropped.get()) == 1 }, time.Second, time.Millisecond)
+
+	// the deadline bounds fn's ctx too
+	f = Submit(p, context.Background(), func(ctx context.Context) (int, error) {
+		_, ok := ctx.Deadline()
+		require.True(t, ok)
+		return 1, nil
+	}, WithTimeout(time.Hour))
+	v, err := f.Get(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+}
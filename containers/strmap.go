@@ -19,18 +19,34 @@ package containers
 import (
 	"errors"
 	"fmt"
+	"hash/maphash"
 	"math"
 	"sort"
 	"strings"
 
-	"github.com/cloudwego/gopkg/internal/hash/maphash"
-	"github.com/cloudwego/gopkg/internal/strstore"
 	"github.com/cloudwego/gopkg/unsafex"
 )
 
-// StrMap represents GC friendly readonly string map implementation.
+// StrMap represents GC friendly string map implementation.
 // type V must NOT contain pointer for performance concern.
+//
+// A StrMap is usually bulk-built with LoadFromSlice or LoadFromMap, and can then be changed
+// with Set, Upsert and Delete. Deleted items are tombstoned, and the map is rehashed a few
+// slots at a time by later changes, which drops tombstones and grows or shrinks the hashtable.
+// Get may be called concurrently as long as nothing changes the map, Item may not.
 type StrMap[V any] struct {
+	strTable[V]
+
+	// old is the table being rehashed into the embedded one, see rehashStep.
+	// Its slots below `rehashIdx` are moved already.
+	old       strTable[V]
+	rehashIdx int
+
+	// for maphash
+	seed maphash.Seed
+}
+
+type strTable[V any] struct {
 	// `data` holds bytes of keys
 	data []byte
 
@@ -40,17 +56,21 @@ type StrMap[V any] struct {
 	// max hashtable ~ 2 billions which means len(items) < the num as well.
 	hashtable []int32 // using int32 for mem efficiency
 
-	// for maphash
-	seed maphash.Seed
+	// number of tombstones in `items`
+	deleted int
 }
 
 type mapItem[V any] struct {
-	off  int
+	off  int    // -1 for tombstones
 	sz   uint32 // 4GB, big enough for key
-	slot uint32
+	hash uint32
+	next int32 // next item with the same slot, or -1
 	v    V
 }
 
+// rehashBatch is the number of slots moved by every change while rehashing.
+const rehashBatch = 16
+
 // New creates a StrMap instance,
 func New[V any]() *StrMap[V] {
 	return &StrMap[V]{seed: maphash.MakeSeed()}
@@ -93,6 +113,9 @@ func (m *StrMap[V]) LoadFromSlice(kk []string, vv []V) error {
 	m.data = m.data[:0]
 	m.items = m.items[:0]
 	m.hashtable = m.hashtable[:0]
+	m.deleted = 0
+	m.old = strTable[V]{}
+	m.rehashIdx = 0
 
 	sz := 0
 	for _, k := range kk {
@@ -115,7 +138,7 @@ func (m *StrMap[V]) LoadFromSlice(kk []string, vv []V) error {
 			mapItem[V]{
 				off:  len(m.data),
 				sz:   uint32(len(k)),
-				slot: uint32(maphash.String(m.seed, k)),
+				hash: uint32(maphash.String(m.seed, k)),
 				v:    v,
 			})
 		m.data = append(m.data, k...)
@@ -126,21 +149,31 @@ func (m *StrMap[V]) LoadFromSlice(kk []string, vv []V) error {
 
 // Len returns the size of map
 func (m *StrMap[V]) Len() int {
-	return len(m.items)
+	return len(m.items) - m.deleted + len(m.old.items) - m.old.deleted
 }
 
 // Item returns the i'th item in map.
 // It panics if i is not in the range [0, Len()).
+//
+// After Set, Upsert or Delete, the first call compacts the map so that items are numbered
+// from 0 again, which costs a full rehash. Since it may change the map, Item is not safe
+// for concurrent use, not even with other Item or Get calls.
 func (m *StrMap[V]) Item(i int) (string, V) {
+	m.compact()
 	e := &m.items[i]
 	return unsafex.BinaryToString(m.data[e.off : e.off+int(e.sz)]), e.v
 }
 
-type itemsBySlot[V any] []mapItem[V]
+type itemsBySlot[V any] struct {
+	items []mapItem[V]
+	slots uint32
+}
 
-func (x itemsBySlot[V]) Len() int           { return len(x) }
-func (x itemsBySlot[V]) Less(i, j int) bool { return x[i].slot < x[j].slot }
-func (x itemsBySlot[V]) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x itemsBySlot[V]) Len() int { return len(x.items) }
+func (x itemsBySlot[V]) Less(i, j int) bool {
+	return x.items[i].hash%x.slots < x.items[j].hash%x.slots
+}
+func (x itemsBySlot[V]) Swap(i, j int) { x.items[i], x.items[j] = x.items[j], x.items[i] }
 
 func (m *StrMap[V]) makeHashtable() {
 	slots := calcHashtableSlots(len(m.items))
@@ -150,62 +183,222 @@ func (m *StrMap[V]) makeHashtable() {
 		m.hashtable = m.hashtable[:slots]
 	}
 
-	// update `slot` of mapItem to fit the size of hashtable
-	for i := range m.items {
-		m.items[i].slot = m.items[i].slot % uint32(slots)
-	}
-
 	// make sure items with the same slot stored together
 	// good for cpu cache
-	sort.Sort(itemsBySlot[V](m.items))
+	sort.Sort(itemsBySlot[V]{items: m.items, slots: uint32(slots)})
 
 	for i := 0; i < len(m.hashtable); i++ {
 		m.hashtable[i] = -1
 	}
-	for i := range m.items {
+	// link backwards, so that every slot points to its 1st item,
+	// and each item to the next one
+	for i := len(m.items) - 1; i >= 0; i-- {
 		e := &m.items[i]
-		if m.hashtable[e.slot] < 0 {
-			// we only need to store the 1st item if hash conflict
-			// since they're already stored together
-			// will check the next item when Get
-			m.hashtable[e.slot] = int32(i)
-		}
+		slot := e.hash % uint32(slots)
+		e.next = m.hashtable[slot]
+		m.hashtable[slot] = int32(i)
 	}
 }
 
 // Get ...
 func (m *StrMap[V]) Get(s string) (t V, ok bool) {
-	slot := uint32(maphash.String(m.seed, s)) % uint32(len(m.hashtable))
-	i := m.hashtable[slot]
-	if i < 0 {
-		return t, false
-	}
-	e := &m.items[i]
-	if string(m.data[e.off:e.off+int(e.sz)]) == s {
+	if e := m.lookup(uint32(maphash.String(m.seed, s)), s); e != nil {
 		return e.v, true
 	}
+	return t, false
+}
 
-	// collision, worst O(n)
-	// coz i always point to the 1st item with the same slot,
-	// can scan till m.items ends or e.slot != slot.
-	for j := i + 1; j < int32(len(m.items)); j++ {
-		e = &m.items[j]
-		if e.slot != slot {
-			break
+// Set sets the value of k, adding k if it's not in the map yet.
+func (m *StrMap[V]) Set(k string, v V) {
+	m.rehashStep()
+	h := uint32(maphash.String(m.seed, k))
+	if e := m.lookup(h, k); e != nil {
+		e.v = v
+		return
+	}
+	m.insert(h, k, v)
+}
+
+// Upsert sets the value of k to f(v, ok), where v is the current value of k and ok reports
+// whether k is in the map, and returns the new value. f must not change the map.
+func (m *StrMap[V]) Upsert(k string, f func(v V, ok bool) V) V {
+	m.rehashStep()
+	h := uint32(maphash.String(m.seed, k))
+	if e := m.lookup(h, k); e != nil {
+		e.v = f(e.v, true)
+		return e.v
+	}
+	var zero V
+	v := f(zero, false)
+	m.insert(h, k, v)
+	return v
+}
+
+// Delete removes k from the map and reports whether it was there.
+// The item is tombstoned until the next rehash.
+func (m *StrMap[V]) Delete(k string) bool {
+	m.rehashStep()
+	h := uint32(maphash.String(m.seed, k))
+	if !m.strTable.remove(h, k) && !m.old.remove(h, k) {
+		return false
+	}
+	if m.deleted > rehashBatch && m.deleted*2 > len(m.items) {
+		// mostly tombstones, compact and shrink
+		m.rehash()
+	}
+	return true
+}
+
+// lookup returns the item of k, whose hash is h, or nil.
+func (m *StrMap[V]) lookup(h uint32, k string) *mapItem[V] {
+	if i := m.strTable.find(h, k); i >= 0 {
+		return &m.items[i]
+	}
+	if i := m.old.find(h, k); i >= 0 {
+		return &m.old.items[i]
+	}
+	return nil
+}
+
+// insert adds k, which must not be in the map, growing the hashtable if needed.
+func (m *StrMap[V]) insert(h uint32, k string, v V) {
+	if len(k) > math.MaxUint32 {
+		panic("key too large")
+	}
+	if len(m.items) >= len(m.hashtable) {
+		m.rehash()
+	}
+	m.add(h, k, v)
+}
+
+// rehash starts moving the items to a new table sized for Len(), see rehashStep.
+// A rehash that is still in progress is finished first.
+func (m *StrMap[V]) rehash() {
+	m.finishRehash()
+	n := m.Len()
+	m.old = m.strTable
+	m.strTable = strTable[V]{
+		items:     make([]mapItem[V], 0, n),
+		hashtable: make([]int32, calcHashtableSlots(2*n)),
+	}
+	for i := range m.hashtable {
+		m.hashtable[i] = -1
+	}
+	m.rehashIdx = 0
+	m.rehashStep()
+}
+
+// rehashStep moves the items of the next rehashBatch slots of the old table, if any,
+// leaving their tombstones behind. The old table is dropped once it's empty.
+func (m *StrMap[V]) rehashStep() {
+	if m.old.hashtable == nil {
+		return
+	}
+	for n := 0; n < rehashBatch && m.rehashIdx < len(m.old.hashtable); n++ {
+		for i := m.old.hashtable[m.rehashIdx]; i >= 0; {
+			e := &m.old.items[i]
+			m.add(e.hash, m.old.key(e), e.v)
+			i = e.next
+			*e = mapItem[V]{off: -1, next: -1}
+			m.old.deleted++
+		}
+		m.old.hashtable[m.rehashIdx] = -1
+		m.rehashIdx++
+	}
+	if m.rehashIdx == len(m.old.hashtable) {
+		m.old = strTable[V]{}
+		m.rehashIdx = 0
+	}
+}
+
+// finishRehash moves all the items left in the old table.
+func (m *StrMap[V]) finishRehash() {
+	for m.old.hashtable != nil {
+		m.rehashStep()
+	}
+}
+
+// compact rehashes the map if it has tombstones, so that its items are contiguous.
+func (m *StrMap[V]) compact() {
+	if m.old.hashtable == nil && m.deleted == 0 {
+		return
+	}
+	m.finishRehash()
+	if m.deleted > 0 {
+		m.rehash()
+		m.finishRehash()
+	}
+}
+
+// find returns the index of the item of k, whose hash is h, or -1.
+func (t *strTable[V]) find(h uint32, k string) int32 {
+	if len(t.hashtable) == 0 {
+		return -1
+	}
+	for i := t.hashtable[h%uint32(len(t.hashtable))]; i >= 0; i = t.items[i].next {
+		e := &t.items[i]
+		if e.hash == h && t.key(e) == k {
+			return i
 		}
-		if string(m.data[e.off:e.off+int(e.sz)]) == s {
-			return e.v, true
+	}
+	return -1
+}
+
+// add appends k at the head of its slot. The hashtable must not be empty.
+func (t *strTable[V]) add(h uint32, k string, v V) {
+	slot := h % uint32(len(t.hashtable))
+	t.items = append(t.items,
+		mapItem[V]{
+			off:  len(t.data),
+			sz:   uint32(len(k)),
+			hash: h,
+			next: t.hashtable[slot],
+			v:    v,
+		})
+	t.data = append(t.data, k...)
+	t.hashtable[slot] = int32(len(t.items) - 1)
+}
+
+// remove unlinks the item of k, whose hash is h, and tombstones it.
+func (t *strTable[V]) remove(h uint32, k string) bool {
+	if len(t.hashtable) == 0 {
+		return false
+	}
+	slot := h % uint32(len(t.hashtable))
+	prev := int32(-1)
+	for i := t.hashtable[slot]; i >= 0; prev, i = i, t.items[i].next {
+		e := &t.items[i]
+		if e.hash != h || t.key(e) != k {
+			continue
 		}
+		if prev < 0 {
+			t.hashtable[slot] = e.next
+		} else {
+			t.items[prev].next = e.next
+		}
+		*e = mapItem[V]{off: -1, next: -1}
+		t.deleted++
+		return true
 	}
-	return t, false
+	return false
+}
+
+// key returns the key of e, which must not be a tombstone.
+func (t *strTable[V]) key(e *mapItem[V]) string {
+	return unsafex.BinaryToString(t.data[e.off : e.off+int(e.sz)])
 }
 
 // String ...
 func (m *StrMap[V]) String() string {
 	b := &strings.Builder{}
 	b.WriteString("{\n")
-	for _, e := range m.items {
-		fmt.Fprintf(b, "%q: %v,\n", string(m.data[e.off:e.off+int(e.sz)]), e.v)
+	for _, t := range []*strTable[V]{&m.old, &m.strTable} {
+		for i := range t.items {
+			e := &t.items[i]
+			if e.off >= 0 {
+				fmt.Fprintf(b, "%q: %v,\n", t.key(e), e.v)
+			}
+		}
 	}
 	b.WriteString("}")
 	return b.String()
@@ -214,23 +407,31 @@ func (m *StrMap[V]) String() string {
 func (m *StrMap[V]) debugString() string {
 	b := &strings.Builder{}
 	b.WriteString("{\n")
-	for _, e := range m.items {
-		fmt.Fprintf(b, "{off:%d, slot:%x, str:%q, v:%v},\n", e.off, e.slot, string(m.data[e.off:e.off+int(e.sz)]), e.v)
+	for _, t := range []*strTable[V]{&m.old, &m.strTable} {
+		for i := range t.items {
+			e := &t.items[i]
+			if e.off < 0 {
+				b.WriteString("{deleted},\n")
+				continue
+			}
+			fmt.Fprintf(b, "{off:%d, hash:%x, next:%d, str:%q, v:%v},\n", e.off, e.hash, e.next, t.key(e), e.v)
+		}
 	}
-	fmt.Fprintf(b, "}(slots=%d, items=%d)", len(m.hashtable), len(m.items))
+	fmt.Fprintf(b, "}(slots=%d, items=%d, deleted=%d, old_slots=%d, rehashed=%d)",
+		len(m.hashtable), len(m.items), m.deleted+m.old.deleted, len(m.old.hashtable), m.rehashIdx)
 	return b.String()
 }
 
-// Str2Str uses StrMap and strstore.StrStore to store map[string]string
+// Str2Str uses StrMap and strStore to store map[string]string
 type Str2Str struct {
 	strMap   *StrMap[int]
-	strStore *strstore.StrStore
+	strStore *strStore
 }
 
 func NewStr2Str() *Str2Str {
 	return &Str2Str{
 		strMap:   New[int](),
-		strStore: strstore.New(),
+		strStore: newStrStore(),
 	}
 }
 
@@ -258,7 +459,7 @@ func (sm *Str2Str) LoadFromSlice(kk, vv []string) error {
 		return errors.New("kv len not match")
 	}
 	if sm.strStore == nil {
-		sm.strStore = strstore.New()
+		sm.strStore = newStrStore()
 	}
 	ids, err := sm.strStore.Load(vv)
 	if err != nil {
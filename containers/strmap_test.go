@@ -95,6 +95,96 @@ func TestStrMapString(t *testing.T) {
 	t.Log(sm.debugString())
 }
 
+func TestStrMapSetDelete(t *testing.T) {
+	ss := randStrings(20, 20000)
+	m := newStdStrMap(ss[:10000])
+	sm := NewFromMap(m)
+
+	check := func() {
+		require.Equal(t, len(m), sm.Len())
+		for i, s := range ss {
+			v0, ok0 := m[s]
+			v1, ok1 := sm.Get(s)
+			require.Equal(t, ok0, ok1, i)
+			require.Equal(t, v0, v1, i)
+		}
+	}
+
+	// add new keys and update existing ones, growing the map
+	for i, s := range ss {
+		m[s] = uint(i)
+		sm.Set(s, uint(i))
+	}
+	check()
+
+	// delete most keys, shrinking it
+	for i, s := range ss {
+		if i%10 != 0 {
+			delete(m, s)
+			require.True(t, sm.Delete(s), i)
+		}
+	}
+	require.False(t, sm.Delete(ss[1]))
+	check()
+
+	// and add them back, while rehashing
+	for i, s := range ss[:5000] {
+		m[s] = uint(i) + 1
+		sm.Set(s, uint(i)+1)
+	}
+	check()
+
+	// Item compacts
+	m0 := make(map[string]uint)
+	for i := 0; i < sm.Len(); i++ {
+		s, v := sm.Item(i)
+		m0[s] = v
+	}
+	require.Equal(t, m, m0)
+	require.Zero(t, sm.deleted)
+	require.Nil(t, sm.old.hashtable)
+	require.Equal(t, len(m), len(sm.items))
+}
+
+func TestStrMapNew(t *testing.T) {
+	sm := New[int]()
+	_, ok := sm.Get("a")
+	require.False(t, ok)
+	require.False(t, sm.Delete("a"))
+
+	sm.Set("a", 1)
+	sm.Set("b", 2)
+	sm.Set("a", 3)
+	require.Equal(t, 2, sm.Len())
+	v, ok := sm.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 3, v)
+
+	require.True(t, sm.Delete("a"))
+	_, ok = sm.Get("a")
+	require.False(t, ok)
+	require.Equal(t, 1, sm.Len())
+	k, v := sm.Item(0)
+	require.Equal(t, "b", k)
+	require.Equal(t, 2, v)
+}
+
+func TestStrMapUpsert(t *testing.T) {
+	sm := NewFromSlice([]string{"a"}, []int{1})
+	incr := func(v int, ok bool) int {
+		if !ok {
+			return 100
+		}
+		return v + 1
+	}
+	require.Equal(t, 2, sm.Upsert("a", incr))
+	require.Equal(t, 100, sm.Upsert("b", incr))
+	require.Equal(t, 101, sm.Upsert("b", incr))
+	v, _ := sm.Get("b")
+	require.Equal(t, 101, v)
+	require.Equal(t, 2, sm.Len())
+}
+
 func TestStr2Str(t *testing.T) {
 	kk := randStrings(20, 100000)
 	vv := randStrings(20, 100000)
@@ -190,6 +280,17 @@ func BenchmarkLoadFromSlice(b *testing.B) {
 	}
 }
 
+func BenchmarkSet(b *testing.B) {
+	ss := randStrings(20, 100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sm := New[int]()
+		for j, s := range ss {
+			sm.Set(s, j)
+		}
+	}
+}
+
 func BenchmarkGet(b *testing.B) {
 	sizes := []int{20, 50, 100}
 	nn := []int{100000, 200000}